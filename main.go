@@ -7,9 +7,11 @@ import (
 	"breakfast/services/apis"
 	"breakfast/services/auth"
 	"breakfast/services/events"
+	"breakfast/services/items"
 	"breakfast/services/overlays"
 	"breakfast/services/pages"
 	"breakfast/services/saas"
+	"breakfast/services/shop"
 	"breakfast/services/viewers"
 	"breakfast/www"
 	"log"
@@ -39,12 +41,15 @@ func main() {
 	apis.RegisterService(app)
 	viewers.RegisterService(app)
 	events.RegisterService(app)
+	items.RegisterService(app)
+	shop.RegisterService(app)
 	overlays.RegisterService(app)
 	pages.RegisterService(app)
 	www.RegisterService(app)
 
 	// Setup jobs
 	auth.RegisterJobs(app, scheduler)
+	events.RegisterServiceJobs(app, scheduler)
 
 	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
 		scheduler.Start()
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Create table of recently received eventsub message ids for deduplication
+		{
+			_, err := db.NewQuery(`
+				CREATE TABLE twitch_eventsub_messages (
+					id TEXT PRIMARY KEY,
+					received TEXT NOT NULL
+				);
+				CREATE INDEX twitch_eventsub_messages_received_idx ON twitch_eventsub_messages (received);
+			`).Execute()
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, nil)
+}
@@ -0,0 +1,130 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Create a new channels collection
+		{
+			dao := daos.New(db)
+
+			collection := &models.Collection{
+				Name:       "channels",
+				Type:       "base",
+				System:     true,
+				ListRule:   types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				ViewRule:   types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				CreateRule: nil,
+				UpdateRule: types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				DeleteRule: types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				Indexes: types.JsonArray[string]{
+					"CREATE UNIQUE INDEX channels_provider_id_idx ON channels (provider, providerId)",
+				},
+				Options: types.JsonMap{},
+				Schema: schema.NewSchema(
+					&schema.SchemaField{
+						Id:          "provider",
+						Name:        "provider",
+						Type:        schema.FieldTypeText,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "providerId",
+						Name:        "providerId",
+						Type:        schema.FieldTypeText,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "username",
+						Name:        "username",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "displayName",
+						Name:        "displayName",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: true,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "avatar",
+						Name:        "avatar",
+						Type:        schema.FieldTypeUrl,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"exceptDomains": nil,
+							"onlyDomains":   nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "label",
+						Name:        "label",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "color",
+						Name:        "color",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+				),
+			}
+
+			collection.SetId("channels")
+
+			{
+				err := dao.SaveCollection(collection)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, nil)
+}
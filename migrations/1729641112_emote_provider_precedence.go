@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/pocketbase/dbx"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Create emote provider precedence setting
+		{
+			_, err := db.Insert("_params", dbx.Params{
+				"id":      security.RandomString(15),
+				"key":     "breakfast-emotes-provider-precedence",
+				"value":   "7tv,bttv,ffz",
+				"created": time.Now().UTC().Format(types.DefaultDateLayout),
+				"updated": time.Now().UTC().Format(types.DefaultDateLayout),
+			}).Execute()
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, nil)
+}
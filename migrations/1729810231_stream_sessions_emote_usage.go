@@ -0,0 +1,128 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Create a new stream_sessions collection
+		{
+			dao := daos.New(db)
+
+			collection := &models.Collection{
+				Name:       "stream_sessions",
+				Type:       "base",
+				System:     true,
+				ListRule:   types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				ViewRule:   types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				CreateRule: nil,
+				UpdateRule: nil,
+				DeleteRule: types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				Indexes: types.JsonArray[string]{
+					"CREATE INDEX stream_sessions_channel_idx ON stream_sessions (channel, started)",
+				},
+				Options: types.JsonMap{},
+				Schema: schema.NewSchema(
+					&schema.SchemaField{
+						Id:          "channel",
+						Name:        "channel",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "channels",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "started",
+						Name:        "started",
+						Type:        schema.FieldTypeDate,
+						Required:    true,
+						Presentable: true,
+						Options: types.JsonMap{
+							"min": "",
+							"max": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "ended",
+						Name:        "ended",
+						Type:        schema.FieldTypeDate,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min": "",
+							"max": "",
+						},
+					},
+				),
+			}
+
+			collection.SetId("stream_sessions")
+
+			{
+				err := dao.SaveCollection(collection)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Emote usage aggregates, counted as chat messages come in. Channels are channel record ids.
+		{
+			_, err := db.NewQuery(`CREATE TABLE emote_usage_daily (
+				day TEXT NOT NULL,
+				channel TEXT NOT NULL,
+				provider TEXT NOT NULL,
+				emoteId TEXT NOT NULL,
+				name TEXT NOT NULL,
+				count INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (day, channel, provider, emoteId)
+			)`).Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		{
+			_, err := db.NewQuery(`CREATE TABLE emote_usage_sessions (
+				session TEXT NOT NULL,
+				provider TEXT NOT NULL,
+				emoteId TEXT NOT NULL,
+				name TEXT NOT NULL,
+				count INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (session, provider, emoteId)
+			)`).Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		{
+			_, err := db.NewQuery(`CREATE TABLE emote_usage_viewers (
+				viewer TEXT NOT NULL,
+				channel TEXT NOT NULL,
+				provider TEXT NOT NULL,
+				emoteId TEXT NOT NULL,
+				name TEXT NOT NULL,
+				count INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (viewer, channel, provider, emoteId)
+			)`).Execute()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, nil)
+}
@@ -0,0 +1,134 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Create a new wallet_transactions collection, a ledger of every change to viewer wallets
+		{
+			dao := daos.New(db)
+
+			collection := &models.Collection{
+				Name:       "wallet_transactions",
+				Type:       "base",
+				System:     true,
+				ListRule:   types.Pointer("viewer.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				ViewRule:   types.Pointer("viewer.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				CreateRule: nil,
+				UpdateRule: nil,
+				DeleteRule: nil,
+				Indexes: types.JsonArray[string]{
+					"CREATE INDEX wallet_transactions_viewer_idx ON wallet_transactions (viewer, created)",
+					"CREATE INDEX wallet_transactions_source_idx ON wallet_transactions (source)",
+				},
+				Options: types.JsonMap{},
+				Schema: schema.NewSchema(
+					&schema.SchemaField{
+						Id:          "viewer",
+						Name:        "viewer",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "viewers",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "currency",
+						Name:        "currency",
+						Type:        schema.FieldTypeText,
+						Required:    true,
+						Presentable: true,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "delta",
+						Name:        "delta",
+						Type:        schema.FieldTypeNumber,
+						Required:    false,
+						Presentable: true,
+						Options: types.JsonMap{
+							"min":       nil,
+							"max":       nil,
+							"noDecimal": true,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "balance",
+						Name:        "balance",
+						Type:        schema.FieldTypeNumber,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":       nil,
+							"max":       nil,
+							"noDecimal": true,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "reason",
+						Name:        "reason",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "source",
+						Name:        "source",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "actor",
+						Name:        "actor",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+				),
+			}
+
+			collection.SetId("wallet_transactions")
+
+			{
+				err := dao.SaveCollection(collection)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, nil)
+}
@@ -0,0 +1,159 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Create a new currencies collection
+		{
+			dao := daos.New(db)
+
+			collection := &models.Collection{
+				Name:       "currencies",
+				Type:       "base",
+				System:     true,
+				ListRule:   types.Pointer(""),
+				ViewRule:   types.Pointer(""),
+				CreateRule: types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				UpdateRule: types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				DeleteRule: types.Pointer("@request.auth.verified = true && @request.auth.collectionName = \"users\""),
+				Indexes: types.JsonArray[string]{
+					"CREATE UNIQUE INDEX currencies_name_idx ON currencies (name)",
+				},
+				Options: types.JsonMap{},
+				Schema: schema.NewSchema(
+					&schema.SchemaField{
+						Id:          "name",
+						Name:        "name",
+						Type:        schema.FieldTypeText,
+						Required:    true,
+						Presentable: true,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "icon",
+						Name:        "icon",
+						Type:        schema.FieldTypeFile,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"mimeTypes": types.JsonArray[string]{
+								"image/jpeg",
+								"image/png",
+								"image/svg+xml",
+								"image/gif",
+								"image/webp",
+							},
+							"thumbs": types.JsonArray[string]{
+								"64x64f",
+							},
+							"maxSelect": 1,
+							"maxSize":   10_000_000, // 10 Megabytes
+							"protected": false,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "startingBalance",
+						Name:        "startingBalance",
+						Type:        schema.FieldTypeNumber,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":       nil,
+							"max":       nil,
+							"noDecimal": true,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "canGoNegative",
+						Name:        "canGoNegative",
+						Type:        schema.FieldTypeBool,
+						Required:    false,
+						Presentable: false,
+						Options:     types.JsonMap{},
+					},
+					&schema.SchemaField{
+						Id:          "rules",
+						Name:        "rules",
+						Type:        schema.FieldTypeJson,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"maxSize": 1_000_000, // 1MB
+						},
+					},
+				),
+			}
+
+			collection.SetId("currencies")
+
+			{
+				err := dao.SaveCollection(collection)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Define the currencies viewers were given before currencies had definitions. Channel points
+		// mirror twitch which holds the real balance, so they're allowed to go negative.
+		{
+			var query []struct {
+				Currency string `db:"currency"`
+			}
+
+			err := db.
+				Select("json_each.key AS currency").
+				Distinct(true).
+				From("viewers, json_each(viewers.wallet, '$')").
+				All(&query)
+			if err != nil {
+				return err
+			}
+
+			currencies := map[string]bool{"dots": true, "channel points": true}
+			for _, row := range query {
+				if row.Currency != "" {
+					currencies[row.Currency] = true
+				}
+			}
+
+			for currency := range currencies {
+				startingBalance := 0
+				if currency == "dots" {
+					startingBalance = 5
+				}
+
+				_, err := db.Insert("currencies", dbx.Params{
+					"id":              security.RandomString(15),
+					"name":            currency,
+					"icon":            "",
+					"startingBalance": startingBalance,
+					"canGoNegative":   currency == "channel points",
+					"rules":           "[]",
+					"created":         time.Now().UTC().Format(types.DefaultDateLayout),
+					"updated":         time.Now().UTC().Format(types.DefaultDateLayout),
+				}).Execute()
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, nil)
+}
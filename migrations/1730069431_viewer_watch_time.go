@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Create table of how long each viewer watched each stream session
+		{
+			_, err := db.NewQuery(`
+				CREATE TABLE viewer_watch_time (
+					session TEXT NOT NULL,
+					viewer TEXT NOT NULL,
+					seconds INTEGER NOT NULL DEFAULT 0,
+					firstSeen TEXT NOT NULL,
+					lastSeen TEXT NOT NULL,
+					PRIMARY KEY (session, viewer)
+				);
+				CREATE INDEX viewer_watch_time_viewer_idx ON viewer_watch_time (viewer);
+			`).Execute()
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, nil)
+}
@@ -0,0 +1,112 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Create a new shop_purchases collection
+		{
+			dao := daos.New(db)
+
+			collection := &models.Collection{
+				Name:       "shop_purchases",
+				Type:       "base",
+				System:     true,
+				ListRule:   types.Pointer("viewer.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				ViewRule:   types.Pointer("viewer.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				CreateRule: nil,
+				UpdateRule: nil,
+				DeleteRule: nil,
+				Indexes: types.JsonArray[string]{
+					"CREATE INDEX shop_purchases_viewer_item_idx ON shop_purchases (viewer, item)",
+					"CREATE INDEX shop_purchases_item_idx ON shop_purchases (item)",
+				},
+				Options: types.JsonMap{},
+				Schema: schema.NewSchema(
+					&schema.SchemaField{
+						Id:          "viewer",
+						Name:        "viewer",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "viewers",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "item",
+						Name:        "item",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "items",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "currency",
+						Name:        "currency",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "price",
+						Name:        "price",
+						Type:        schema.FieldTypeNumber,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":       0,
+							"max":       nil,
+							"noDecimal": true,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "actor",
+						Name:        "actor",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+				),
+			}
+
+			collection.SetId("shop_purchases")
+
+			{
+				err := dao.SaveCollection(collection)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, nil)
+}
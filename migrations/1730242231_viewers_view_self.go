@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Let signed in viewers see their own record and items
+		{
+			dao := daos.New(db)
+
+			viewers, err := dao.FindCollectionByNameOrId("viewers")
+			if err != nil {
+				return err
+			}
+
+			viewers.ViewRule = types.Pointer("id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")")
+
+			{
+				err := dao.SaveCollection(viewers)
+				if err != nil {
+					return err
+				}
+			}
+
+			viewerItems, err := dao.FindCollectionByNameOrId("viewer_items")
+			if err != nil {
+				return err
+			}
+
+			viewerItems.ViewRule = types.Pointer("owner.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")")
+
+			{
+				err := dao.SaveCollection(viewerItems)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, nil)
+}
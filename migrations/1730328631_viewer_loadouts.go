@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Give every owned profile item an explicit equipped state. Items without one were all
+		// shown before, so the newest base and the oldest accessories up to the slot limit stay on
+		// unless they were taken off.
+		{
+			var rows []struct {
+				Id       string `db:"id"`
+				Owner    string `db:"owner"`
+				Type     string `db:"type"`
+				Equipped bool   `db:"equipped"`
+			}
+
+			err := db.
+				Select(
+					"vi.id",
+					"vi.owner",
+					"i.type",
+					"COALESCE(json_extract(vi.meta, '$.equipped'), TRUE) as equipped",
+				).
+				From("viewer_items as vi").
+				InnerJoin("items as i", dbx.NewExp("vi.item = i.id")).
+				Where(dbx.NewExp("i.type = 'PROFILE_BASE' OR i.type = 'PROFILE_ACCESSORY'")).
+				OrderBy("vi.owner", "vi.created ASC", "vi.id ASC").
+				All(&rows)
+			if err != nil {
+				return err
+			}
+
+			const maxAccessorySlots = 8
+
+			patches := map[string]map[string]any{}
+			latestBase := map[string]string{}
+			latestEquippedBase := map[string]string{}
+			nextSlot := map[string]int{}
+
+			for _, row := range rows {
+				if row.Type == "PROFILE_BASE" {
+					patches[row.Id] = map[string]any{"equipped": false}
+					latestBase[row.Owner] = row.Id
+					if row.Equipped {
+						latestEquippedBase[row.Owner] = row.Id
+					}
+					continue
+				}
+
+				slot := nextSlot[row.Owner]
+				if !row.Equipped || slot >= maxAccessorySlots {
+					patches[row.Id] = map[string]any{"equipped": false, "slot": nil}
+					continue
+				}
+
+				patches[row.Id] = map[string]any{"equipped": true, "slot": slot}
+				nextSlot[row.Owner] = slot + 1
+			}
+
+			// Everyone who owns a base keeps exactly one on
+			for owner, base := range latestBase {
+				if equipped, exists := latestEquippedBase[owner]; exists {
+					base = equipped
+				}
+
+				patches[base] = map[string]any{"equipped": true}
+			}
+
+			for id, patch := range patches {
+				data, err := json.Marshal(patch)
+				if err != nil {
+					return err
+				}
+
+				_, err = db.
+					NewQuery("UPDATE viewer_items SET meta = json_patch(CASE WHEN json_type(meta) = 'object' THEN meta ELSE '{}' END, {:patch}) WHERE id = {:id}").
+					Bind(dbx.Params{"patch": string(data), "id": id}).
+					Execute()
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, nil)
+}
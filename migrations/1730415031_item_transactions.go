@@ -0,0 +1,155 @@
+package migrations
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Add consumable items, which are used up
+		{
+			dao := daos.New(db)
+
+			items, err := dao.FindCollectionByNameOrId("items")
+			if err != nil {
+				return err
+			}
+
+			field := items.Schema.GetFieldByName("type")
+			options, ok := field.Options.(*schema.SelectOptions)
+			if !ok {
+				return errors.New("items type field isn't a select")
+			}
+
+			if !slices.Contains(options.Values, "CONSUMABLE") {
+				options.Values = append(options.Values, "CONSUMABLE")
+			}
+
+			{
+				err := dao.SaveCollection(items)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Create a new item_transactions collection, a ledger of items given to, used and taken from viewers
+		{
+			dao := daos.New(db)
+
+			collection := &models.Collection{
+				Name:       "item_transactions",
+				Type:       "base",
+				System:     true,
+				ListRule:   types.Pointer("viewer.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				ViewRule:   types.Pointer("viewer.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				CreateRule: nil,
+				UpdateRule: nil,
+				DeleteRule: nil,
+				Indexes: types.JsonArray[string]{
+					"CREATE INDEX item_transactions_viewer_idx ON item_transactions (viewer, created)",
+					"CREATE INDEX item_transactions_item_idx ON item_transactions (item)",
+					"CREATE INDEX item_transactions_source_idx ON item_transactions (source)",
+				},
+				Options: types.JsonMap{},
+				Schema: schema.NewSchema(
+					&schema.SchemaField{
+						Id:          "viewer",
+						Name:        "viewer",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "viewers",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "item",
+						Name:        "item",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: true,
+						Options: types.JsonMap{
+							"collectionId":  "items",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "delta",
+						Name:        "delta",
+						Type:        schema.FieldTypeNumber,
+						Required:    false,
+						Presentable: true,
+						Options: types.JsonMap{
+							"min":       nil,
+							"max":       nil,
+							"noDecimal": true,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "reason",
+						Name:        "reason",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "source",
+						Name:        "source",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "actor",
+						Name:        "actor",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+				),
+			}
+
+			collection.SetId("item_transactions")
+
+			{
+				err := dao.SaveCollection(collection)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, nil)
+}
@@ -0,0 +1,187 @@
+package migrations
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Add stackable field to items collection
+		{
+			dao := daos.New(db)
+
+			col, err := dao.FindCollectionByNameOrId("items")
+			if err != nil {
+				return err
+			}
+
+			col.Schema.AddField(&schema.SchemaField{
+				Id:          "stackable",
+				Name:        "stackable",
+				Type:        schema.FieldTypeBool,
+				Required:    false,
+				Presentable: false,
+				Options:     types.JsonMap{},
+			})
+
+			{
+				err := dao.SaveCollection(col)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Add quantity field to viewer_items collection, every existing row is one item
+		{
+			dao := daos.New(db)
+
+			col, err := dao.FindCollectionByNameOrId("viewer_items")
+			if err != nil {
+				return err
+			}
+
+			col.Schema.AddField(&schema.SchemaField{
+				Id:          "quantity",
+				Name:        "quantity",
+				Type:        schema.FieldTypeNumber,
+				Required:    false,
+				Presentable: false,
+				Options: types.JsonMap{
+					"min":       nil,
+					"max":       nil,
+					"noDecimal": true,
+				},
+			})
+
+			{
+				err := dao.SaveCollection(col)
+				if err != nil {
+					return err
+				}
+			}
+
+			{
+				_, err := db.NewQuery("UPDATE viewer_items SET quantity = 1").Execute()
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Create a new item_trades collection
+		{
+			dao := daos.New(db)
+
+			collection := &models.Collection{
+				Name:       "item_trades",
+				Type:       "base",
+				System:     true,
+				ListRule:   types.Pointer("sender.id = @request.auth.id || recipient.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				ViewRule:   types.Pointer("sender.id = @request.auth.id || recipient.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				CreateRule: nil,
+				UpdateRule: nil,
+				DeleteRule: nil,
+				Indexes: types.JsonArray[string]{
+					"CREATE INDEX item_trades_sender_idx ON item_trades (sender, status)",
+					"CREATE INDEX item_trades_recipient_idx ON item_trades (recipient, status)",
+				},
+				Options: types.JsonMap{},
+				Schema: schema.NewSchema(
+					&schema.SchemaField{
+						Id:          "sender",
+						Name:        "sender",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "viewers",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "recipient",
+						Name:        "recipient",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "viewers",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "offered",
+						Name:        "offered",
+						Type:        schema.FieldTypeJson,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"maxSize": 1_000_000, // 1MB
+						},
+					},
+					&schema.SchemaField{
+						Id:          "requested",
+						Name:        "requested",
+						Type:        schema.FieldTypeJson,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"maxSize": 1_000_000, // 1MB
+						},
+					},
+					&schema.SchemaField{
+						Id:          "status",
+						Name:        "status",
+						Type:        schema.FieldTypeSelect,
+						Required:    true,
+						Presentable: true,
+						Options: types.JsonMap{
+							"maxSelect": 1,
+							"values": types.JsonArray[string]{
+								"PENDING",
+								"ACCEPTED",
+								"DECLINED",
+								"CANCELLED",
+							},
+						},
+					},
+					&schema.SchemaField{
+						Id:          "actor",
+						Name:        "actor",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+				),
+			}
+
+			collection.SetId("item_trades")
+
+			{
+				err := dao.SaveCollection(collection)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}, nil)
+}
@@ -0,0 +1,242 @@
+package migrations
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	m "github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func init() {
+	m.Register(func(db dbx.Builder) error {
+		// Add loot boxes, which drop other items from their loot table
+		{
+			dao := daos.New(db)
+
+			items, err := dao.FindCollectionByNameOrId("items")
+			if err != nil {
+				return err
+			}
+
+			field := items.Schema.GetFieldByName("type")
+			options, ok := field.Options.(*schema.SelectOptions)
+			if !ok {
+				return errors.New("items type field isn't a select")
+			}
+
+			if !slices.Contains(options.Values, "LOOT_BOX") {
+				options.Values = append(options.Values, "LOOT_BOX")
+			}
+
+			items.Schema.AddField(&schema.SchemaField{
+				Id:          "lootTable",
+				Name:        "lootTable",
+				Type:        schema.FieldTypeJson,
+				Required:    false,
+				Presentable: false,
+				Options: types.JsonMap{
+					"maxSize": 1_000_000, // 1MB
+				},
+			})
+
+			{
+				err := dao.SaveCollection(items)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Create a new loot_drops collection, every loot box opened with what it needs to replay the draw
+		{
+			dao := daos.New(db)
+
+			collection := &models.Collection{
+				Name:       "loot_drops",
+				Type:       "base",
+				System:     true,
+				ListRule:   types.Pointer("viewer.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				ViewRule:   types.Pointer("viewer.id = @request.auth.id || (@request.auth.verified = true && @request.auth.collectionName = \"users\")"),
+				CreateRule: nil,
+				UpdateRule: nil,
+				DeleteRule: nil,
+				Indexes: types.JsonArray[string]{
+					"CREATE INDEX loot_drops_viewer_idx ON loot_drops (viewer, created)",
+					"CREATE INDEX loot_drops_loot_box_idx ON loot_drops (lootBox)",
+				},
+				Options: types.JsonMap{},
+				Schema: schema.NewSchema(
+					&schema.SchemaField{
+						Id:          "viewer",
+						Name:        "viewer",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "viewers",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "lootBox",
+						Name:        "lootBox",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "items",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "item",
+						Name:        "item",
+						Type:        schema.FieldTypeRelation,
+						Required:    true,
+						Presentable: false,
+						Options: types.JsonMap{
+							"collectionId":  "items",
+							"cascadeDelete": true,
+							"minSelect":     nil,
+							"maxSelect":     1,
+							"displayFields": nil,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "quantity",
+						Name:        "quantity",
+						Type:        schema.FieldTypeNumber,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":       nil,
+							"max":       nil,
+							"noDecimal": true,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "rarity",
+						Name:        "rarity",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "pity",
+						Name:        "pity",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "seed",
+						Name:        "seed",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "roll",
+						Name:        "roll",
+						Type:        schema.FieldTypeNumber,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":       nil,
+							"max":       nil,
+							"noDecimal": true,
+						},
+					},
+					&schema.SchemaField{
+						Id:          "lootTable",
+						Name:        "lootTable",
+						Type:        schema.FieldTypeJson,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"maxSize": 1_000_000, // 1MB
+						},
+					},
+					&schema.SchemaField{
+						Id:          "source",
+						Name:        "source",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+					&schema.SchemaField{
+						Id:          "actor",
+						Name:        "actor",
+						Type:        schema.FieldTypeText,
+						Required:    false,
+						Presentable: false,
+						Options: types.JsonMap{
+							"min":     nil,
+							"max":     nil,
+							"pattern": "",
+						},
+					},
+				),
+			}
+
+			collection.SetId("loot_drops")
+
+			{
+				err := dao.SaveCollection(collection)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Create table of how many loot boxes each viewer opened since they last got a rarity
+		{
+			_, err := db.NewQuery(`
+				CREATE TABLE loot_pity (
+					viewer TEXT NOT NULL,
+					lootBox TEXT NOT NULL,
+					rarity TEXT NOT NULL,
+					count INTEGER NOT NULL DEFAULT 0,
+					PRIMARY KEY (viewer, lootBox, rarity)
+				);
+			`).Execute()
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, nil)
+}
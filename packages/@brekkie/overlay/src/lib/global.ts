@@ -9,10 +9,12 @@ interface BreakfastGlobal {
      * Listen for events from the breakfast SSE endpoint (chat messages, redeems, etc.)
      *
      * @param listener Function to call of each event
+     * @param options.channel Only receive events from these channel ids or usernames
      * @returns A function to unlisten the provided listener
      */
     listen: (
       listener: (event: BreakfastEvent) => void | Promise<void>,
+      options?: { channel?: string | string[] },
     ) => Promise<() => Promise<void>>;
   };
 }
@@ -1,8 +1,16 @@
-import type { BreakfastEvent } from "./event.js";
+import type { BreakfastEvent, Item, Viewer } from "./event.js";
 
 export type Action = {
   type: string;
   emit: string;
   inputs: Record<string, any>;
   event: BreakfastEvent | null;
+  /**
+   * The viewer whose item ran the action, missing for actions sent from the dashboard
+   */
+  viewer?: Viewer | null;
+  /**
+   * The item that ran the action, missing for actions sent from the dashboard
+   */
+  item?: Item;
 };
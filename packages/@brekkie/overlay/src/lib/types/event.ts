@@ -1,8 +1,10 @@
 import type { Action } from "./action.js";
 
 export type Channel = {
+  id: string;
   username: string;
   displayName: string;
+  platform: string;
 };
 
 export type Viewer = {
@@ -18,7 +20,7 @@ export type Chatter = {
   displayName: string;
 };
 
-export type Platforms = "twitch" | "actions";
+export type Platforms = "twitch" | "actions" | "shop" | "items";
 
 export type ActionEvent = {
   id: string | null;
@@ -27,6 +29,57 @@ export type ActionEvent = {
   data: Action;
 };
 
+export type ChatMessageImage = {
+  // services/events/types/chat.go
+  url: string;
+  /**
+   * 0 when the provider doesn't say
+   */
+  width: number;
+  height: number;
+  scale: number;
+  format: "webp" | "avif" | "gif" | "png";
+  animated: boolean;
+  /**
+   * Empty when the image isn't made for a chat theme
+   */
+  theme: "dark" | "light" | "";
+};
+
+export type ChatMessageFragment = {
+  type: string;
+  text: string;
+  images: ChatMessageImage[];
+  /**
+   * Set on emote fragments
+   */
+  emote: {
+    id: string;
+    provider: string;
+  } | null;
+  /**
+   * Zero width emotes drawn on top of this emote, in draw order
+   */
+  overlays: ChatMessageFragment[] | null;
+  /**
+   * Set on cheermote fragments
+   */
+  cheermote: {
+    prefix: string;
+    bits: number;
+    tier: number;
+    color: string;
+  } | null;
+};
+
+export type ChatMessageBadge = {
+  setId: string;
+  id: string;
+  info: string;
+  title: string;
+  images: ChatMessageImage[];
+};
+
 export type ChatMessageEvent = {
   id: string | null;
   type: "chat-message";
@@ -35,6 +88,10 @@ export type ChatMessageEvent = {
     // services/events/types/chat.go
     id: string;
     channel: Channel;
+    /**
+     * The channel the message was sent in when it came through shared chat
+     */
+    source: Channel | null;
     chatter: Chatter;
     viewer: Viewer | null;
     reply: {
@@ -44,12 +101,17 @@ export type ChatMessageEvent = {
     } | null;
     text: string;
     color: string;
-    fragments: {
-      type: string;
-      text: string;
-      images: { url: string }[];
-    }[];
+    fragments: ChatMessageFragment[];
     features: string[];
+    badges: ChatMessageBadge[];
+    isBroadcaster: boolean;
+    isModerator: boolean;
+    isVip: boolean;
+    isSubscriber: boolean;
+    /**
+     * Total bits cheered in the message
+     */
+    bits: number;
   };
   /**
    * This field is only used on the client side to flag a message deleted by a delete event
@@ -63,6 +125,7 @@ export type ChatMessageDeleteEvent = {
   platform: Platforms;
   data: {
     id: string;
+    channel: Channel;
   };
 };
 
@@ -101,9 +164,139 @@ export type CurrencySpentEvent = {
   };
 };
 
+export type FollowEvent = {
+  id: string | null;
+  type: "follow";
+  platform: Platforms;
+  data: {
+    channel: Channel;
+    chatter: Chatter;
+    viewer: Viewer | null;
+  };
+};
+
+export type RaidEvent = {
+  id: string | null;
+  type: "raid";
+  platform: Platforms;
+  data: {
+    from: Channel;
+    to: Channel;
+    viewer: Viewer | null;
+    viewers: number;
+  };
+};
+
+export type EmotesUpdatedEvent = {
+  id: string | null;
+  type: "emotes-updated";
+  platform: Platforms;
+  data: {
+    // services/events/types/emotes.go
+    channel: Channel;
+    provider: string;
+    added: string[];
+    removed: string[];
+  };
+};
+
+export type Item = {
+  // services/events/types/items.go
+  id: string;
+  type: string;
+  label: string;
+  description: string;
+  image: string;
+};
+
+export type ItemPurchasedEvent = {
+  id: string | null;
+  type: "item-purchased";
+  platform: Platforms;
+  data: {
+    id: string;
+    /**
+     * Set when the item was bought through chat
+     */
+    channel: Channel | null;
+    viewer: Viewer | null;
+    item: Item;
+    currency: string;
+    price: number;
+  };
+};
+
+export type ItemGrantedEvent = {
+  id: string | null;
+  type: "item-granted";
+  platform: Platforms;
+  data: {
+    /**
+     * The id of the ledger record of the grant
+     */
+    id: string;
+    viewer: Viewer | null;
+    item: Item;
+    quantity: number;
+  };
+};
+
+export type TradedItem = {
+  item: Item;
+  quantity: number;
+};
+
+export type ItemTradedEvent = {
+  id: string | null;
+  type: "item-traded";
+  platform: Platforms;
+  data: {
+    id: string;
+    sender: Viewer | null;
+    recipient: Viewer | null;
+    /**
+     * What the sender gave, empty when the sender only asked for items
+     */
+    offered: TradedItem[];
+    /**
+     * What the recipient gave, empty for gifts
+     */
+    requested: TradedItem[];
+  };
+};
+
+export type LootBoxOpenedEvent = {
+  id: string | null;
+  type: "loot-box-opened";
+  platform: Platforms;
+  data: {
+    /**
+     * The id of the loot drop record, which keeps the seed and loot table to replay the draw
+     */
+    id: string;
+    viewer: Viewer | null;
+    lootBox: Item;
+    item: Item;
+    quantity: number;
+    rarity: "COMMON" | "UNCOMMON" | "RARE" | "EPIC" | "LEGENDARY";
+    /**
+     * The rarity the draw was limited to after too many boxes without it, empty for normal draws
+     */
+    pity: string;
+    seed: string;
+  };
+};
+
 export type BreakfastEvent =
   | ActionEvent
   | ChatMessageEvent
   | ChatMessageDeleteEvent
   | SubscriptionEvent
-  | CurrencySpentEvent;
+  | CurrencySpentEvent
+  | FollowEvent
+  | RaidEvent
+  | EmotesUpdatedEvent
+  | ItemPurchasedEvent
+  | ItemGrantedEvent
+  | ItemTradedEvent
+  | LootBoxOpenedEvent;
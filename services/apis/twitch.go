@@ -236,13 +236,272 @@ func GetTwitchUserByLogin(login string) (*TwitchUser, error) {
 	return &user, nil
 }
 
-func GetTwitchGlobalEmotes() (*any, error) {
+// Makes an authorized GET request to the helix api and unmarshals the response into out
+func getHelix(url string, out any) error {
 	{
 		err := refreshToken()
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest("GET", url, strings.NewReader(""))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+twitchToken)
+	req.Header.Set("Client-Id", twitchClient)
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != 200 {
+		return errors.New("helix request returned error: " + response.Status + " " + string(body))
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+type TwitchEmote struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Images struct {
+		Url1x string `json:"url_1x"`
+		Url2x string `json:"url_2x"`
+		Url4x string `json:"url_4x"`
+	} `json:"images"`
+	Tier       string   `json:"tier"`
+	EmoteType  string   `json:"emote_type"`
+	EmoteSetId string   `json:"emote_set_id"`
+	Format     []string `json:"format"`
+	Scale      []string `json:"scale"`
+	ThemeMode  []string `json:"theme_mode"`
+}
+
+type TwitchEmoteSet struct {
+	Data     []TwitchEmote `json:"data"`
+	Template string        `json:"template"`
+}
+
+func GetTwitchGlobalEmotes() (*TwitchEmoteSet, error) {
+	var set TwitchEmoteSet
+	err := getHelix("https://api.twitch.tv/helix/chat/emotes/global", &set)
+	if err != nil {
+		return nil, err
+	}
+
+	return &set, nil
+}
+
+func GetTwitchChannelEmotes(broadcasterId string) (*TwitchEmoteSet, error) {
+	var set TwitchEmoteSet
+	err := getHelix("https://api.twitch.tv/helix/chat/emotes?broadcaster_id="+url.QueryEscape(broadcasterId), &set)
+	if err != nil {
+		return nil, err
+	}
+
+	return &set, nil
+}
+
+type TwitchChatBadgeVersion struct {
+	Id          string `json:"id"`
+	ImageUrl1x  string `json:"image_url_1x"`
+	ImageUrl2x  string `json:"image_url_2x"`
+	ImageUrl4x  string `json:"image_url_4x"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+type TwitchChatBadgeSet struct {
+	SetId    string                   `json:"set_id"`
+	Versions []TwitchChatBadgeVersion `json:"versions"`
+}
+
+func GetTwitchGlobalChatBadges() ([]TwitchChatBadgeSet, error) {
+	var response struct {
+		Data []TwitchChatBadgeSet `json:"data"`
+	}
+	err := getHelix("https://api.twitch.tv/helix/chat/badges/global", &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
+
+func GetTwitchChannelChatBadges(broadcasterId string) ([]TwitchChatBadgeSet, error) {
+	var response struct {
+		Data []TwitchChatBadgeSet `json:"data"`
+	}
+	err := getHelix("https://api.twitch.tv/helix/chat/badges?broadcaster_id="+url.QueryEscape(broadcasterId), &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
+
+// Image urls keyed by scale ("1", "1.5", "2", "3", "4")
+type TwitchCheermoteImages struct {
+	Animated map[string]string `json:"animated"`
+	Static   map[string]string `json:"static"`
+}
+
+type TwitchCheermoteTier struct {
+	MinBits int    `json:"min_bits"`
+	Id      string `json:"id"`
+	Color   string `json:"color"`
+	Images  struct {
+		Dark  TwitchCheermoteImages `json:"dark"`
+		Light TwitchCheermoteImages `json:"light"`
+	} `json:"images"`
+	CanCheer bool `json:"can_cheer"`
+}
+
+type TwitchCheermote struct {
+	Prefix string                `json:"prefix"`
+	Tiers  []TwitchCheermoteTier `json:"tiers"`
+	Type   string                `json:"type"`
+	Order  int                   `json:"order"`
+}
+
+func GetTwitchCheermotes(broadcasterId string) ([]TwitchCheermote, error) {
+	var response struct {
+		Data []TwitchCheermote `json:"data"`
+	}
+	err := getHelix("https://api.twitch.tv/helix/bits/cheermotes?broadcaster_id="+url.QueryEscape(broadcasterId), &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Data, nil
+}
+
+var ErrTwitchNotModerator = errors.New("no stored twitch token can moderate the channel")
+
+type TwitchChatter struct {
+	UserId    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	UserName  string `json:"user_name"`
+}
+
+// Gets the stored twitch user tokens with the twitch id they belong to. The broadcaster's own
+// token comes first since it can always moderate its channel.
+func getTwitchModeratorTokens(broadcasterId string) ([]struct {
+	TwitchId    string `db:"twitchId"`
+	AccessToken string `db:"accessToken"`
+}, error) {
+	tokens := []struct {
+		TwitchId    string `db:"twitchId"`
+		AccessToken string `db:"accessToken"`
+	}{}
+
+	// The select builder quotes order by expressions as column names, so the query is written out
+	err := services.App.Dao().DB().
+		NewQuery(`SELECT e.providerId as twitchId, t.accessToken FROM tokens as t
+			INNER JOIN _externalAuths as e ON e.id = t.identity
+			WHERE t.provider = 'twitch'
+			ORDER BY e.providerId = {:broadcasterId} DESC`).
+		Bind(dbx.Params{"broadcasterId": broadcasterId}).
+		All(&tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Gets everyone connected to a channel's chat. Needs a token with the moderator:read:chatters
+// scope from the broadcaster or one of their moderators.
+func GetTwitchChatters(broadcasterId string) ([]TwitchChatter, error) {
+	if twitchClient == "" {
+		err := getTwitchSettings()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	panic("not implemented")
+	tokens, err := getTwitchModeratorTokens(broadcasterId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		chatters := []TwitchChatter{}
+		cursor := ""
+		forbidden := false
+
+		for {
+			query := url.Values{}
+			query.Set("broadcaster_id", broadcasterId)
+			query.Set("moderator_id", token.TwitchId)
+			query.Set("first", "1000")
+			if cursor != "" {
+				query.Set("after", cursor)
+			}
+
+			req, err := http.NewRequest("GET", "https://api.twitch.tv/helix/chat/chatters?"+query.Encode(), strings.NewReader(""))
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+			req.Header.Set("Client-Id", twitchClient)
+
+			response, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return nil, err
+			}
+
+			body, err := io.ReadAll(response.Body)
+			response.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+
+			// The token is missing the scope or its user doesn't moderate the channel
+			if response.StatusCode == 401 || response.StatusCode == 403 {
+				forbidden = true
+				break
+			}
+
+			if response.StatusCode != 200 {
+				return nil, errors.New("get chatters request returned error: " + response.Status + " " + string(body))
+			}
+
+			var page struct {
+				Data       []TwitchChatter `json:"data"`
+				Pagination struct {
+					Cursor string `json:"cursor"`
+				} `json:"pagination"`
+			}
+			{
+				err := json.Unmarshal(body, &page)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			chatters = append(chatters, page.Data...)
+			if page.Pagination.Cursor == "" {
+				break
+			}
+			cursor = page.Pagination.Cursor
+		}
+
+		if !forbidden {
+			return chatters, nil
+		}
+	}
+
+	return nil, ErrTwitchNotModerator
 }
@@ -0,0 +1,45 @@
+package badges
+
+import (
+	"breakfast/services/events/channels"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/cron"
+)
+
+func RegisterService(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		err := RefreshTwitchGlobalBadges()
+		if err != nil {
+			app.Logger().Error("BADGES Failed to get twitch global badges", "error", err.Error())
+		}
+		GetAllTwitchBadges()
+
+		return nil
+	})
+
+	// Load badges for channels as they start being watched
+	channels.OnWatch(func(channel channels.WatchedChannel) {
+		if channel.Provider != "twitch" {
+			return
+		}
+
+		err := RefreshTwitchChannelBadges(channel.ProviderId)
+		if err != nil {
+			app.Logger().Error(
+				"BADGES Failed to get twitch badges for channel",
+				"twitchId", channel.ProviderId,
+				"error", err.Error(),
+			)
+		}
+	})
+}
+
+func RegisterJobs(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		ScheduleTwitchBadgeRefresh(app, scheduler)
+
+		return nil
+	})
+}
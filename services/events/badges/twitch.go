@@ -0,0 +1,138 @@
+package badges
+
+import (
+	"breakfast/services"
+	"breakfast/services/apis"
+	"breakfast/services/events/channels"
+	"breakfast/services/events/types"
+	"sync"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/cron"
+)
+
+// Badge versions keyed by set id then version id
+type badgeSets map[string]map[string]apis.TwitchChatBadgeVersion
+
+var twitchGlobals badgeSets = make(badgeSets)
+var twitchChannels map[string]badgeSets = make(map[string]badgeSets)
+var twitchLock sync.RWMutex
+
+func toBadgeSets(sets []apis.TwitchChatBadgeSet) badgeSets {
+	byId := make(badgeSets, len(sets))
+	for _, set := range sets {
+		versions := make(map[string]apis.TwitchChatBadgeVersion, len(set.Versions))
+		for _, version := range set.Versions {
+			versions[version.Id] = version
+		}
+		byId[set.SetId] = versions
+	}
+
+	return byId
+}
+
+func RefreshTwitchGlobalBadges() error {
+	sets, err := apis.GetTwitchGlobalChatBadges()
+	if err != nil {
+		return err
+	}
+
+	twitchLock.Lock()
+	twitchGlobals = toBadgeSets(sets)
+	twitchLock.Unlock()
+
+	return nil
+}
+
+func RefreshTwitchChannelBadges(broadcasterId string) error {
+	services.App.Logger().Debug(
+		"BADGES Refreshing twitch channel badges",
+		"twitchId", broadcasterId,
+	)
+
+	sets, err := apis.GetTwitchChannelChatBadges(broadcasterId)
+	if err != nil {
+		return err
+	}
+
+	twitchLock.Lock()
+	twitchChannels[broadcasterId] = toBadgeSets(sets)
+	twitchLock.Unlock()
+
+	return nil
+}
+
+func GetAllTwitchBadges() error {
+	for _, channel := range channels.AllForProvider("twitch") {
+		err := RefreshTwitchChannelBadges(channel.ProviderId)
+		if err != nil {
+			services.App.Logger().Error(
+				"BADGES Failed to get twitch badges for channel",
+				"twitchId", channel.ProviderId,
+				"error", err.Error(),
+			)
+			continue
+		}
+	}
+
+	return nil
+}
+
+// Finds a badge version, channel badges (like subscriber badges) override global badges
+func lookupTwitchBadge(broadcasterId string, setId string, id string) (apis.TwitchChatBadgeVersion, bool) {
+	twitchLock.RLock()
+	defer twitchLock.RUnlock()
+
+	if version, exists := twitchChannels[broadcasterId][setId][id]; exists {
+		return version, true
+	}
+
+	version, exists := twitchGlobals[setId][id]
+	return version, exists
+}
+
+// Resolves the images of a chatter's badges in a channel. Badges that can't be found are
+// kept without images.
+func ResolveTwitchBadges(broadcasterId string, badges []types.ChatMessageBadge) []types.ChatMessageBadge {
+	resolved := make([]types.ChatMessageBadge, 0, len(badges))
+	for _, badge := range badges {
+		badge.Images = []types.ChatMessageImage{}
+
+		version, exists := lookupTwitchBadge(broadcasterId, badge.SetId, badge.Id)
+		if exists {
+			badge.Title = version.Title
+			for _, image := range []struct {
+				Url   string
+				Scale int
+			}{
+				{version.ImageUrl1x, 1},
+				{version.ImageUrl2x, 2},
+				{version.ImageUrl4x, 4},
+			} {
+				if image.Url == "" {
+					continue
+				}
+
+				// Twitch badges are 18px square at 1x
+				badge.Images = append(badge.Images, types.ChatMessageImage{
+					Url:    image.Url,
+					Width:  18 * image.Scale,
+					Height: 18 * image.Scale,
+					Scale:  float64(image.Scale),
+					Format: types.ImageFormatPng,
+				})
+			}
+		}
+
+		resolved = append(resolved, badge)
+	}
+
+	return resolved
+}
+
+func ScheduleTwitchBadgeRefresh(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	scheduler.MustAdd("badges-refresh-twitch", "0 */6 * * *", func() {
+		RefreshTwitchGlobalBadges()
+		GetAllTwitchBadges()
+	})
+}
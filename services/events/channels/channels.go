@@ -0,0 +1,260 @@
+package channels
+
+import (
+	"breakfast/services"
+	"breakfast/services/apis"
+	"breakfast/services/events/types"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+/*
+Label - a name set by the user to tell channels apart in overlays (e.g. the co-streamer's team)
+Color - a color set by the user to tell channels apart in overlays
+*/
+type WatchedChannel struct {
+	Id          string `json:"id"`
+	Provider    string `json:"provider"`
+	ProviderId  string `json:"providerId"`
+	Username    string `json:"username"`
+	DisplayName string `json:"displayName"`
+	Avatar      string `json:"avatar"`
+	Label       string `json:"label"`
+	Color       string `json:"color"`
+}
+
+func (w WatchedChannel) Channel() types.Channel {
+	return types.Channel{
+		Id:          w.ProviderId,
+		Username:    w.Username,
+		DisplayName: w.DisplayName,
+		Platform:    w.Provider,
+	}
+}
+
+// Called after a channel starts being watched or has its metadata refreshed
+type WatchHook func(channel WatchedChannel)
+
+var watched map[string]WatchedChannel = make(map[string]WatchedChannel)
+var watchedLock sync.RWMutex
+var watchHooks []WatchHook = []WatchHook{}
+
+func key(provider string, providerId string) string {
+	return provider + "-" + providerId
+}
+
+func OnWatch(hook WatchHook) {
+	watchHooks = append(watchHooks, hook)
+}
+
+func fromRecord(record *models.Record) WatchedChannel {
+	return WatchedChannel{
+		Id:          record.Id,
+		Provider:    record.GetString("provider"),
+		ProviderId:  record.GetString("providerId"),
+		Username:    record.GetString("username"),
+		DisplayName: record.GetString("displayName"),
+		Avatar:      record.GetString("avatar"),
+		Label:       record.GetString("label"),
+		Color:       record.GetString("color"),
+	}
+}
+
+func store(channel WatchedChannel) {
+	watchedLock.Lock()
+	watched[key(channel.Provider, channel.ProviderId)] = channel
+	watchedLock.Unlock()
+}
+
+func forget(provider string, providerId string) {
+	watchedLock.Lock()
+	delete(watched, key(provider, providerId))
+	watchedLock.Unlock()
+}
+
+func Get(provider string, providerId string) (WatchedChannel, bool) {
+	watchedLock.RLock()
+	defer watchedLock.RUnlock()
+
+	channel, exists := watched[key(provider, providerId)]
+	return channel, exists
+}
+
+func All() []WatchedChannel {
+	watchedLock.RLock()
+	defer watchedLock.RUnlock()
+
+	all := make([]WatchedChannel, 0, len(watched))
+	for _, channel := range watched {
+		// Skip channels claimed by Ensure that haven't finished watching
+		if channel.Id == "" {
+			continue
+		}
+		all = append(all, channel)
+	}
+
+	return all
+}
+
+// Gets a watched channel by its channel record id
+func GetById(id string) (WatchedChannel, bool) {
+	for _, channel := range All() {
+		if channel.Id == id {
+			return channel, true
+		}
+	}
+
+	return WatchedChannel{}, false
+}
+
+// Finds a watched channel by its provider id or username
+func Find(idOrUsername string) (WatchedChannel, bool) {
+	for _, channel := range All() {
+		if channel.ProviderId == idOrUsername || strings.EqualFold(channel.Username, idOrUsername) {
+			return channel, true
+		}
+	}
+
+	return WatchedChannel{}, false
+}
+
+func AllForProvider(provider string) []WatchedChannel {
+	all := []WatchedChannel{}
+	for _, channel := range All() {
+		if channel.Provider == provider {
+			all = append(all, channel)
+		}
+	}
+
+	return all
+}
+
+// Starts watching a channel, or refreshes its metadata if it's already watched. User set
+// fields like the label and color are left untouched.
+func Watch(provider string, providerId string) (WatchedChannel, error) {
+	collection, err := services.App.Dao().FindCollectionByNameOrId("channels")
+	if err != nil {
+		return WatchedChannel{}, err
+	}
+
+	record, err := services.App.Dao().FindFirstRecordByFilter(
+		"channels",
+		"provider = {:provider} && providerId = {:providerId}",
+		dbx.Params{"provider": provider, "providerId": providerId},
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		record = models.NewRecord(collection)
+		record.RefreshId()
+		record.Set("provider", provider)
+		record.Set("providerId", providerId)
+	} else if err != nil {
+		return WatchedChannel{}, err
+	}
+
+	switch provider {
+	case "twitch":
+		user, err := apis.GetTwitchUserById(providerId)
+		if err != nil {
+			return WatchedChannel{}, err
+		}
+
+		record.Set("username", user.Login)
+		record.Set("displayName", user.DisplayName)
+		record.Set("avatar", user.ProfileImageUrl)
+	default:
+		return WatchedChannel{}, errors.New("provider is not supported: " + provider)
+	}
+
+	{
+		err := services.App.Dao().SaveRecord(record)
+		if err != nil {
+			return WatchedChannel{}, err
+		}
+	}
+
+	channel := fromRecord(record)
+	store(channel)
+
+	for _, hook := range watchHooks {
+		hook(channel)
+	}
+
+	return channel, nil
+}
+
+// Watches a channel in the background if it isn't already
+func Ensure(provider string, providerId string) {
+	if _, exists := Get(provider, providerId); exists {
+		return
+	}
+
+	// Claim the channel so messages arriving before the watch completes don't also start watching it
+	store(WatchedChannel{Provider: provider, ProviderId: providerId})
+
+	go func() {
+		_, err := Watch(provider, providerId)
+		if err != nil {
+			forget(provider, providerId)
+			services.App.Logger().Error(
+				"CHANNELS Failed to watch channel",
+				"provider", provider,
+				"providerId", providerId,
+				"error", err.Error(),
+			)
+		}
+	}()
+}
+
+// Loads watched channels from the database and starts watching any broadcaster with
+// a twitch eventsub subscription that isn't watched yet
+func Load() error {
+	records, err := services.App.Dao().FindRecordsByExpr("channels")
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		store(fromRecord(record))
+	}
+
+	var query []struct {
+		BroadcasterId string `db:"broadcasterId"`
+	}
+	{
+		err := services.App.Dao().DB().
+			Select(`COALESCE(
+				json_extract(tes.config, '$.condition.broadcaster_user_id'),
+				json_extract(tes.config, '$.condition.to_broadcaster_user_id'),
+				json_extract(tes.config, '$.condition.from_broadcaster_user_id')
+			) as broadcasterId`).
+			Distinct(true).
+			From("twitch_event_subscriptions as tes").
+			Where(dbx.NewExp("broadcasterId IS NOT NULL")).
+			All(&query)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, row := range query {
+		if _, exists := Get("twitch", row.BroadcasterId); exists {
+			continue
+		}
+
+		_, err := Watch("twitch", row.BroadcasterId)
+		if err != nil {
+			services.App.Logger().Error(
+				"CHANNELS Failed to watch subscribed twitch channel",
+				"twitchId", row.BroadcasterId,
+				"error", err.Error(),
+			)
+		}
+	}
+
+	return nil
+}
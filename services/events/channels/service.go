@@ -0,0 +1,54 @@
+package channels
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func RegisterService(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		err := Load()
+		if err != nil {
+			app.Logger().Error("CHANNELS Failed to load watched channels", "error", err.Error())
+		}
+
+		return nil
+	})
+
+	// Keep the registry in sync with edits made through the admin ui or records api
+	app.OnRecordAfterUpdateRequest("channels").Add(func(e *core.RecordUpdateEvent) error {
+		store(fromRecord(e.Record))
+		return nil
+	})
+
+	app.OnRecordAfterDeleteRequest("channels").Add(func(e *core.RecordDeleteEvent) error {
+		forget(e.Record.GetString("provider"), e.Record.GetString("providerId"))
+		return nil
+	})
+
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/api/breakfast/channels", func(c echo.Context) error {
+			// Validate user is authenticated
+			info := apis.RequestInfo(c)
+			user := info.AuthRecord
+
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			if user.Collection().Id != "users" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			return c.JSON(200, map[string]any{
+				"channels": All(),
+			})
+		})
+
+		return nil
+	})
+}
@@ -0,0 +1,186 @@
+package earnings
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/channels"
+	"breakfast/services/events/streams"
+	"breakfast/services/events/types"
+	"breakfast/services/viewers"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// How long after they were last seen in chat a viewer still counts as watching
+const WatchingTimeout = 10 * time.Minute
+
+// Viewers cooling down from a rule, keyed by currency, rule and viewer. Each entry expires with
+// the rule's cooldown.
+var cooldowns *cache.Cache = cache.New(cache.NoExpiration, 10*time.Minute)
+
+// When each viewer was last seen in a channel, keyed by channel record id then viewer id
+var watching map[string]map[string]time.Time = make(map[string]map[string]time.Time)
+var watchingLock sync.Mutex
+
+func credit(viewerId string, currency string, amount int, reason string, source string) {
+	if amount <= 0 {
+		return
+	}
+
+	_, err := viewers.Credit(viewerId, viewers.WalletChange{
+		Currency: currency,
+		Amount:   amount,
+		Reason:   reason,
+		Source:   source,
+		Actor:    "earnings",
+	})
+	if err != nil {
+		services.App.Logger().Error(
+			"EARNINGS Failed to credit viewer",
+			"viewer", viewerId,
+			"currency", currency,
+			"error", err.Error(),
+		)
+	}
+}
+
+// Checks and starts a rule's cooldown for a viewer. Returns false while it's cooling down.
+func takeCooldown(currency string, rule viewers.EarningRule, viewerId string) bool {
+	if rule.Cooldown <= 0 {
+		return true
+	}
+
+	// Keyed on the rule itself so reordering or removing other rules doesn't move cooldowns around
+	key := fmt.Sprintf("%s-%s-%d-%d-%d-%s", currency, rule.On, rule.Amount, rule.Cooldown, rule.PerBits, viewerId)
+
+	// Only adds when the viewer isn't cooling down already
+	err := cooldowns.Add(key, true, time.Duration(rule.Cooldown)*time.Second)
+	return err == nil
+}
+
+// Marks a viewer as watching a channel, by its channel record id
+func MarkWatching(channelId string, viewerId string) {
+	watchingLock.Lock()
+	defer watchingLock.Unlock()
+
+	if _, exists := watching[channelId]; !exists {
+		watching[channelId] = map[string]time.Time{}
+	}
+	watching[channelId][viewerId] = time.Now()
+}
+
+// Viewers seen in a channel within the watching timeout, forgetting the ones that weren't
+func watchingViewers(channelId string) []string {
+	watchingLock.Lock()
+	defer watchingLock.Unlock()
+
+	viewerIds := []string{}
+	for viewerId, seen := range watching[channelId] {
+		if time.Since(seen) > WatchingTimeout {
+			delete(watching[channelId], viewerId)
+			continue
+		}
+
+		viewerIds = append(viewerIds, viewerId)
+	}
+
+	return viewerIds
+}
+
+func handleChatMessage(source string, message *types.ChatMessage) {
+	// Shared chat messages from other channels are earned on in the channel they were sent in
+	if message.Viewer == nil || message.Source != nil {
+		return
+	}
+
+	if channel, exists := channels.Get(message.Channel.Platform, message.Channel.Id); exists && channel.Id != "" {
+		MarkWatching(channel.Id, message.Viewer.Id)
+	}
+
+	for currency, rules := range viewers.CurrenciesEarnedOn(viewers.EarnOnChatMessage) {
+		for _, rule := range rules {
+			if !takeCooldown(currency, rule, message.Viewer.Id) {
+				continue
+			}
+
+			credit(message.Viewer.Id, currency, rule.Amount, "Chatted", source)
+		}
+	}
+
+	if message.Bits <= 0 {
+		return
+	}
+
+	for currency, rules := range viewers.CurrenciesEarnedOn(viewers.EarnOnCheer) {
+		for _, rule := range rules {
+			amount := rule.Amount
+			if rule.PerBits > 0 {
+				amount = rule.Amount * (message.Bits / rule.PerBits)
+			}
+
+			credit(message.Viewer.Id, currency, amount, "Cheered "+strconv.Itoa(message.Bits)+" bits", source)
+		}
+	}
+}
+
+func handleSubscription(source string, subscription *types.Subscription) {
+	if subscription.Viewer == nil {
+		return
+	}
+
+	for currency, rules := range viewers.CurrenciesEarnedOn(viewers.EarnOnSubscription) {
+		for _, rule := range rules {
+			credit(subscription.Viewer.Id, currency, rule.Amount, "Subscribed", source)
+		}
+	}
+}
+
+func handleRaid(source string, raid *types.Raid) {
+	if raid.Viewer == nil {
+		return
+	}
+
+	for currency, rules := range viewers.CurrenciesEarnedOn(viewers.EarnOnRaid) {
+		for _, rule := range rules {
+			credit(raid.Viewer.Id, currency, rule.Amount, "Raided with "+strconv.Itoa(raid.Viewers)+" viewers", source)
+		}
+	}
+}
+
+// Applies the earning rules for an emitted event
+func HandleEvent(provider string, providerId string, event types.BreakfastEvent) {
+	source := provider + ":" + providerId
+
+	switch data := event.Data.(type) {
+	case *types.ChatMessage:
+		handleChatMessage(source, data)
+	case *types.Subscription:
+		handleSubscription(source, data)
+	case *types.Raid:
+		handleRaid(source, data)
+	}
+}
+
+// Credits every viewer watching a live channel for a minute watched
+func CreditWatchMinute() {
+	earned := viewers.CurrenciesEarnedOn(viewers.EarnOnWatchMinute)
+	if len(earned) == 0 {
+		return
+	}
+
+	minute := time.Now().UTC().Truncate(time.Minute).Format(time.RFC3339)
+	for _, session := range streams.AllLive() {
+		source := "watch:" + session.Id + ":" + minute
+
+		for _, viewerId := range watchingViewers(session.Channel) {
+			for currency, rules := range earned {
+				for _, rule := range rules {
+					credit(viewerId, currency, rule.Amount, "Watched live", source)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package earnings
+
+import (
+	"breakfast/services/events/listener"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/cron"
+)
+
+func RegisterService(app *pocketbase.PocketBase) {
+	listener.OnEvent(HandleEvent)
+}
+
+func RegisterJobs(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		scheduler.MustAdd("earnings-watch-minute", "* * * * *", CreditWatchMinute)
+
+		return nil
+	})
+}
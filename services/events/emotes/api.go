@@ -0,0 +1,195 @@
+package emotes
+
+import (
+	"breakfast/services/events/channels"
+	"breakfast/services/viewers"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// The merged emotes used for a channel, including the twitch emotes twitch parses itself.
+// Emotes from the index win over twitch emotes with the same name.
+func ListEmotes(platform string, platformId string) []Emote {
+	index := getIndex(platform, platformId)
+
+	emotes := make([]Emote, 0, len(index))
+	for _, emote := range index {
+		emote.Images = proxyEmoteImages(emote.Provider, emote.Id, emote.Images)
+		emotes = append(emotes, emote)
+	}
+
+	if platform == "twitch" {
+		for _, emote := range TwitchEmotes(platformId) {
+			if _, exists := index[emote.Name]; exists {
+				continue
+			}
+
+			emotes = append(emotes, emote)
+		}
+	}
+
+	sort.Slice(emotes, func(i, j int) bool {
+		return emotes[i].Name < emotes[j].Name
+	})
+
+	return emotes
+}
+
+func registerEmoteAPIs(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/api/breakfast/emotes", func(c echo.Context) error {
+			info := apis.RequestInfo(c)
+			user := info.AuthRecord
+
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			if user.Collection().Id != "users" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			// Without a channel only global emotes are listed
+			platform, platformId := "", ""
+			if channelQuery := c.QueryParam("channel"); channelQuery != "" {
+				channel, exists := channels.Find(channelQuery)
+				if !exists {
+					return c.JSON(http.StatusNotFound, map[string]string{"message": "Channel is not watched"})
+				}
+
+				platform, platformId = channel.Provider, channel.ProviderId
+			}
+
+			provider := c.QueryParam("provider")
+			search := strings.ToLower(c.QueryParam("search"))
+
+			emotes := []Emote{}
+			for _, emote := range ListEmotes(platform, platformId) {
+				if provider != "" && emote.Provider != provider {
+					continue
+				}
+
+				if search != "" && !strings.Contains(strings.ToLower(emote.Name), search) {
+					continue
+				}
+
+				emotes = append(emotes, emote)
+			}
+
+			return c.JSON(200, map[string]any{
+				"emotes": emotes,
+			})
+		})
+
+		e.Router.GET("/api/breakfast/emotes/usage", func(c echo.Context) error {
+			// Viewers can only see the emotes they've used
+			viewerId := c.QueryParam("viewer")
+			permission := viewers.PermissionUser
+			if viewerId != "" {
+				permission = viewers.PermissionViewer
+			}
+
+			if !viewers.HasPermission(c, viewerId, permission) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			query := UsageQuery{
+				Range:  c.QueryParam("range"),
+				Viewer: viewerId,
+				Limit:  25,
+			}
+
+			if query.Range == "" {
+				query.Range = UsageRangeAll
+			}
+
+			if limitQuery := c.QueryParam("limit"); limitQuery != "" {
+				limit, err := strconv.Atoi(limitQuery)
+				if err != nil || limit < 1 || limit > 100 {
+					return c.JSON(http.StatusBadRequest, map[string]string{"message": "Limit must be between 1 and 100"})
+				}
+				query.Limit = limit
+			}
+
+			// Without a channel usage across all channels is counted together
+			if channelQuery := c.QueryParam("channel"); channelQuery != "" {
+				channel, exists := channels.Find(channelQuery)
+				if !exists {
+					return c.JSON(http.StatusNotFound, map[string]string{"message": "Channel is not watched"})
+				}
+
+				query.Channel = &channel
+			}
+
+			usage, err := TopEmotes(query)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"message": "Failed to get emote usage", "error": err.Error()})
+			}
+
+			return c.JSON(200, map[string]any{
+				"emotes": usage,
+			})
+		})
+
+		e.Router.POST("/api/breakfast/emotes/refresh", func(c echo.Context) error {
+			info := apis.RequestInfo(c)
+			user := info.AuthRecord
+
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			if user.Collection().Id != "users" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			// A channel only reloads that channel's emotes, otherwise everything is reloaded
+			if channelQuery := c.QueryParam("channel"); channelQuery != "" {
+				channel, exists := channels.Find(channelQuery)
+				if !exists {
+					return c.JSON(http.StatusNotFound, map[string]string{"message": "Channel is not watched"})
+				}
+
+				RefreshChannelEmotes(channel.Provider, channel.ProviderId)
+				if channel.Provider == "twitch" {
+					err := errors.Join(
+						RefreshTwitchChannelEmotes(channel.ProviderId),
+						RefreshTwitchCheermotes(channel.ProviderId),
+					)
+					if err != nil {
+						return c.JSON(http.StatusBadGateway, map[string]string{"message": "Failed to refresh twitch emotes", "error": err.Error()})
+					}
+				}
+
+				return c.JSON(200, map[string]string{
+					"message": "OK",
+				})
+			}
+
+			RefreshGlobalEmotes()
+			RefreshAllChannelEmotes()
+			{
+				err := RefreshTwitchGlobalEmotes()
+				if err != nil {
+					app.Logger().Error("EMOTES Failed to get twitch global emotes", "error", err.Error())
+				}
+			}
+			GetAllTwitchEmotes()
+			GetAllTwitchCheermotes()
+
+			return c.JSON(200, map[string]string{
+				"message": "OK",
+			})
+		})
+
+		return nil
+	})
+}
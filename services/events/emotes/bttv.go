@@ -0,0 +1,126 @@
+package emotes
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/types"
+	"strconv"
+)
+
+const ProviderBTTV = "bttv"
+
+/*
+ImageType - the format of the emote images (png, gif or webp)
+Width - width at 1x, only set for emotes that aren't 28px square
+Height - height at 1x, only set for emotes that aren't 28px square
+*/
+type BTTVEmote struct {
+	Id        string `json:"id"`
+	Code      string `json:"code"`
+	ImageType string `json:"imageType"`
+	Animated  bool   `json:"animated"`
+	UserId    string `json:"userId"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+}
+
+func (e BTTVEmote) Url(size string) string {
+	return "https://cdn.betterttv.net/emote/" + e.Id + "/" + size
+}
+
+func (e BTTVEmote) Emote() Emote {
+	width, height := e.Width, e.Height
+	if width == 0 || height == 0 {
+		width, height = 28, 28
+	}
+
+	images := make([]types.ChatMessageImage, 0, 3)
+	for _, scale := range []int{1, 2, 3} {
+		images = append(images, types.ChatMessageImage{
+			Url:      e.Url(strconv.Itoa(scale) + "x"),
+			Width:    width * scale,
+			Height:   height * scale,
+			Scale:    float64(scale),
+			Format:   e.ImageType,
+			Animated: e.Animated,
+		})
+	}
+
+	return Emote{
+		Id:       e.Id,
+		Name:     e.Code,
+		Provider: ProviderBTTV,
+		Images:   images,
+	}
+}
+
+/*
+ChannelEmotes - emotes uploaded by the channel
+SharedEmotes - emotes from other users added to the channel
+*/
+type BTTVUser struct {
+	Id            string      `json:"id"`
+	ChannelEmotes []BTTVEmote `json:"channelEmotes"`
+	SharedEmotes  []BTTVEmote `json:"sharedEmotes"`
+}
+
+type bttvProvider struct {
+	emoteStore
+}
+
+func init() {
+	RegisterProvider(&bttvProvider{})
+}
+
+func (p *bttvProvider) Name() string {
+	return ProviderBTTV
+}
+
+func (p *bttvProvider) LoadGlobal() error {
+	var bttvEmotes []BTTVEmote
+	_, err := getJSON("https://api.betterttv.net/3/cached/emotes/global", &bttvEmotes)
+	if err != nil {
+		return err
+	}
+
+	emotes := make([]Emote, 0, len(bttvEmotes))
+	for _, emote := range bttvEmotes {
+		emotes = append(emotes, emote.Emote())
+	}
+
+	p.setGlobal(emotes)
+
+	return nil
+}
+
+func (p *bttvProvider) LoadChannel(platform string, platformId string) error {
+	var user BTTVUser
+	found, err := getJSON("https://api.betterttv.net/3/cached/users/"+platform+"/"+platformId, &user)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		services.App.Logger().Debug(
+			"EMOTES User does not have bttv emotes",
+			"provider", platform,
+			"providerId", platformId,
+		)
+
+		// Clear emotes the channel had before they were removed
+		p.setChannel(platform, platformId, nil)
+		return nil
+	}
+
+	// Channel emotes are added last so they win over shared emotes with the same code
+	emotes := make([]Emote, 0, len(user.SharedEmotes)+len(user.ChannelEmotes))
+	for _, emote := range user.SharedEmotes {
+		emotes = append(emotes, emote.Emote())
+	}
+	for _, emote := range user.ChannelEmotes {
+		emotes = append(emotes, emote.Emote())
+	}
+
+	p.setChannel(platform, platformId, emotes)
+
+	return nil
+}
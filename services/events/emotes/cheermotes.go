@@ -0,0 +1,118 @@
+package emotes
+
+import (
+	"breakfast/services"
+	"breakfast/services/apis"
+	"breakfast/services/events/channels"
+	"breakfast/services/events/types"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Cheermotes of each channel keyed by lowercased prefix, tiers sorted by min bits
+var twitchCheermotes map[string]map[string]apis.TwitchCheermote = make(map[string]map[string]apis.TwitchCheermote)
+var twitchCheermotesLock sync.RWMutex
+
+func RefreshTwitchCheermotes(broadcasterId string) error {
+	services.App.Logger().Debug(
+		"EMOTES Refreshing twitch cheermotes",
+		"twitchId", broadcasterId,
+	)
+
+	cheermotes, err := apis.GetTwitchCheermotes(broadcasterId)
+	if err != nil {
+		return err
+	}
+
+	byPrefix := make(map[string]apis.TwitchCheermote, len(cheermotes))
+	for _, cheermote := range cheermotes {
+		sort.Slice(cheermote.Tiers, func(i, j int) bool {
+			return cheermote.Tiers[i].MinBits < cheermote.Tiers[j].MinBits
+		})
+		byPrefix[strings.ToLower(cheermote.Prefix)] = cheermote
+	}
+
+	twitchCheermotesLock.Lock()
+	twitchCheermotes[broadcasterId] = byPrefix
+	twitchCheermotesLock.Unlock()
+
+	return nil
+}
+
+func GetAllTwitchCheermotes() error {
+	for _, channel := range channels.AllForProvider("twitch") {
+		err := RefreshTwitchCheermotes(channel.ProviderId)
+		if err != nil {
+			services.App.Logger().Error(
+				"EMOTES Failed to get twitch cheermotes for channel",
+				"twitchId", channel.ProviderId,
+				"error", err.Error(),
+			)
+			continue
+		}
+	}
+
+	return nil
+}
+
+func cheermoteImages(images apis.TwitchCheermoteImages, theme string) []types.ChatMessageImage {
+	resolved := []types.ChatMessageImage{}
+	for _, variant := range []struct {
+		Urls     map[string]string
+		Format   string
+		Animated bool
+	}{
+		{images.Animated, types.ImageFormatGif, true},
+		{images.Static, types.ImageFormatPng, false},
+	} {
+		for _, scale := range []string{"1", "1.5", "2", "3", "4"} {
+			url, exists := variant.Urls[scale]
+			if !exists {
+				continue
+			}
+
+			// Cheermotes are 28px square at 1x
+			value, _ := strconv.ParseFloat(scale, 64)
+			resolved = append(resolved, types.ChatMessageImage{
+				Url:      url,
+				Width:    int(28 * value),
+				Height:   int(28 * value),
+				Scale:    value,
+				Format:   variant.Format,
+				Animated: variant.Animated,
+				Theme:    theme,
+			})
+		}
+	}
+
+	return resolved
+}
+
+// Resolves a cheermote to the tier for the amount of bits cheered. Returns false if the
+// channel's cheermotes aren't loaded or the prefix isn't a cheermote.
+func ResolveTwitchCheermote(broadcasterId string, prefix string, bits int) (*types.ChatMessageCheermote, []types.ChatMessageImage, bool) {
+	twitchCheermotesLock.RLock()
+	cheermote, exists := twitchCheermotes[broadcasterId][strings.ToLower(prefix)]
+	twitchCheermotesLock.RUnlock()
+
+	if !exists || len(cheermote.Tiers) == 0 {
+		return nil, nil, false
+	}
+
+	tier := cheermote.Tiers[0]
+	for _, candidate := range cheermote.Tiers {
+		if candidate.MinBits > bits {
+			break
+		}
+		tier = candidate
+	}
+
+	return &types.ChatMessageCheermote{
+		Prefix: cheermote.Prefix,
+		Bits:   bits,
+		Tier:   tier.MinBits,
+		Color:  tier.Color,
+	}, append(cheermoteImages(tier.Images.Dark, "dark"), cheermoteImages(tier.Images.Light, "light")...), true
+}
@@ -0,0 +1,148 @@
+package emotes
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/types"
+	"strconv"
+)
+
+const ProviderFFZ = "ffz"
+
+/*
+Width - width of the 1x image
+Height - height of the 1x image
+Urls - static image urls keyed by scale ("1", "2", "4")
+Animated - animated image urls keyed by scale, nil when the emote isn't animated
+Modifier - the emote is drawn on top of the emote before it
+*/
+type FFZEmote struct {
+	Id       int               `json:"id"`
+	Name     string            `json:"name"`
+	Width    int               `json:"width"`
+	Height   int               `json:"height"`
+	Urls     map[string]string `json:"urls"`
+	Animated map[string]string `json:"animated"`
+	Modifier bool              `json:"modifier"`
+}
+
+func (e FFZEmote) Emote() Emote {
+	// Animated images are served as webp, static ones as png
+	urls := e.Urls
+	format := types.ImageFormatPng
+	if e.Animated != nil {
+		urls = e.Animated
+		format = types.ImageFormatWebp
+	}
+
+	images := make([]types.ChatMessageImage, 0, len(urls))
+	for _, scale := range []int{1, 2, 4} {
+		if url, exists := urls[strconv.Itoa(scale)]; exists {
+			images = append(images, types.ChatMessageImage{
+				Url:      url,
+				Width:    e.Width * scale,
+				Height:   e.Height * scale,
+				Scale:    float64(scale),
+				Format:   format,
+				Animated: e.Animated != nil,
+			})
+		}
+	}
+
+	return Emote{
+		Id:        strconv.Itoa(e.Id),
+		Name:      e.Name,
+		Provider:  ProviderFFZ,
+		Images:    images,
+		ZeroWidth: e.Modifier,
+	}
+}
+
+type FFZEmoteSet struct {
+	Id        int        `json:"id"`
+	Title     string     `json:"title"`
+	Emoticons []FFZEmote `json:"emoticons"`
+}
+
+type FFZRoom struct {
+	Room struct {
+		Id       int    `json:"_id"`
+		TwitchId int    `json:"twitch_id"`
+		Set      int    `json:"set"`
+		Name     string `json:"id"`
+	} `json:"room"`
+	Sets map[string]FFZEmoteSet `json:"sets"`
+}
+
+func ffzEmotes(sets ...FFZEmoteSet) []Emote {
+	emotes := []Emote{}
+	for _, set := range sets {
+		for _, emote := range set.Emoticons {
+			emotes = append(emotes, emote.Emote())
+		}
+	}
+
+	return emotes
+}
+
+type ffzProvider struct {
+	emoteStore
+}
+
+func init() {
+	RegisterProvider(&ffzProvider{})
+}
+
+func (p *ffzProvider) Name() string {
+	return ProviderFFZ
+}
+
+func (p *ffzProvider) LoadGlobal() error {
+	var global struct {
+		DefaultSets []int                  `json:"default_sets"`
+		Sets        map[string]FFZEmoteSet `json:"sets"`
+	}
+	_, err := getJSON("https://api.frankerfacez.com/v1/set/global", &global)
+	if err != nil {
+		return err
+	}
+
+	sets := []FFZEmoteSet{}
+	for _, setId := range global.DefaultSets {
+		if set, exists := global.Sets[strconv.Itoa(setId)]; exists {
+			sets = append(sets, set)
+		}
+	}
+
+	p.setGlobal(ffzEmotes(sets...))
+
+	return nil
+}
+
+func (p *ffzProvider) LoadChannel(platform string, platformId string) error {
+	// FFZ rooms are only looked up by twitch id
+	if platform != "twitch" {
+		return nil
+	}
+
+	var room FFZRoom
+	found, err := getJSON("https://api.frankerfacez.com/v1/room/id/"+platformId, &room)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		services.App.Logger().Debug(
+			"EMOTES User does not have ffz emotes",
+			"provider", platform,
+			"providerId", platformId,
+		)
+
+		// Clear emotes the channel had before they were removed
+		p.setChannel(platform, platformId, nil)
+		return nil
+	}
+
+	p.setChannel(platform, platformId, ffzEmotes(room.Sets[strconv.Itoa(room.Room.Set)]))
+
+	return nil
+}
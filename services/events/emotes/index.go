@@ -0,0 +1,132 @@
+package emotes
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/channels"
+	"sync"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/cron"
+)
+
+// Emotes of every provider merged by name, so emotifying is a single map lookup per word
+// no matter how many emotes are loaded. Channel indexes include the global emotes.
+var globalIndex map[string]Emote = make(map[string]Emote)
+var channelIndexes map[string]map[string]Emote = make(map[string]map[string]Emote)
+var indexLock sync.RWMutex
+
+// Merges emotes so earlier providers in the precedence win name collisions, and channel
+// emotes win over global emotes of the same provider
+func buildIndex(platform string, platformId string, scopes ...Scope) map[string]Emote {
+	precedence := GetProviderPrecedence()
+	index := make(map[string]Emote)
+
+	for i := len(precedence) - 1; i >= 0; i-- {
+		provider, exists := GetProvider(precedence[i])
+		if !exists {
+			continue
+		}
+
+		for _, scope := range scopes {
+			for _, emote := range provider.Emotes(scope, platform, platformId) {
+				index[emote.Name] = emote
+			}
+		}
+	}
+
+	return index
+}
+
+func rebuildChannelIndex(platform string, platformId string) {
+	index := buildIndex(platform, platformId, ScopeGlobal, ScopeChannel)
+
+	indexLock.Lock()
+	channelIndexes[channelKey(platform, platformId)] = index
+	indexLock.Unlock()
+}
+
+// Rebuilds the global index and every channel index, used when global emotes or the
+// provider precedence change
+func rebuildIndexes() {
+	global := buildIndex("", "", ScopeGlobal)
+
+	indexLock.Lock()
+	globalIndex = global
+	indexLock.Unlock()
+
+	for _, channel := range channels.All() {
+		rebuildChannelIndex(channel.Provider, channel.ProviderId)
+	}
+}
+
+// Gets the merged emotes for a channel, channels without loaded emotes get the global emotes
+func getIndex(platform string, platformId string) map[string]Emote {
+	indexLock.RLock()
+	defer indexLock.RUnlock()
+
+	if index, exists := channelIndexes[channelKey(platform, platformId)]; exists {
+		return index
+	}
+
+	return globalIndex
+}
+
+func LookupEmote(platform string, platformId string, name string) (Emote, bool) {
+	emote, exists := getIndex(platform, platformId)[name]
+	return emote, exists
+}
+
+func RefreshGlobalEmotes() {
+	for _, name := range ProviderNames() {
+		provider, _ := GetProvider(name)
+
+		err := provider.LoadGlobal()
+		if err != nil {
+			services.App.Logger().Error(
+				"EMOTES Failed to get global emotes",
+				"emoteProvider", name,
+				"error", err.Error(),
+			)
+		}
+	}
+
+	rebuildIndexes()
+}
+
+func RefreshChannelEmotes(platform string, platformId string) {
+	services.App.Logger().Debug(
+		"EMOTES Refreshing emotes",
+		"provider", platform,
+		"providerId", platformId,
+	)
+
+	for _, name := range ProviderNames() {
+		provider, _ := GetProvider(name)
+
+		err := provider.LoadChannel(platform, platformId)
+		if err != nil {
+			services.App.Logger().Error(
+				"EMOTES Failed to get channel emotes",
+				"emoteProvider", name,
+				"provider", platform,
+				"providerId", platformId,
+				"error", err.Error(),
+			)
+		}
+	}
+
+	rebuildChannelIndex(platform, platformId)
+}
+
+func RefreshAllChannelEmotes() {
+	for _, channel := range channels.All() {
+		RefreshChannelEmotes(channel.Provider, channel.ProviderId)
+	}
+}
+
+func ScheduleEmoteRefresh(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	scheduler.MustAdd("emotes-refresh", "0 */6 * * *", func() {
+		RefreshGlobalEmotes()
+		RefreshAllChannelEmotes()
+	})
+}
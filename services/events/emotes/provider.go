@@ -0,0 +1,131 @@
+package emotes
+
+import (
+	"breakfast/services/events/types"
+	"sync"
+)
+
+type Scope string
+
+const ScopeGlobal Scope = "global"
+const ScopeChannel Scope = "channel"
+
+/*
+Provider - the emote provider the emote is from (e.g. 7tv)
+Scope - whether the emote is available everywhere or only in a channel
+ZeroWidth - the emote is drawn on top of the emote before it instead of beside it
+*/
+type Emote struct {
+	Id        string                   `json:"id"`
+	Name      string                   `json:"name"`
+	Provider  string                   `json:"provider"`
+	Scope     Scope                    `json:"scope"`
+	Images    []types.ChatMessageImage `json:"images"`
+	ZeroWidth bool                     `json:"zeroWidth"`
+}
+
+// A source of emotes used to emotify chat messages. Platform is the streaming platform of
+// the channel (e.g. twitch) and platformId the channel's id on that platform.
+type EmoteProvider interface {
+	// Name used in the provider precedence setting and on emotes
+	Name() string
+	// Loads or refreshes the emotes available in every channel
+	LoadGlobal() error
+	// Loads or refreshes the emotes of a channel
+	LoadChannel(platform string, platformId string) error
+	// Finds an emote by name, channel emotes take priority over global emotes
+	Lookup(platform string, platformId string, name string) (Emote, bool)
+	// All the loaded emotes of a scope, platform and platformId are ignored for the global scope
+	Emotes(scope Scope, platform string, platformId string) []Emote
+}
+
+var providers map[string]EmoteProvider = make(map[string]EmoteProvider)
+var providerOrder []string = []string{}
+
+// Registers an emote provider. Should be called from init.
+func RegisterProvider(provider EmoteProvider) {
+	if _, exists := providers[provider.Name()]; exists {
+		panic("emote provider registered multiple times: " + provider.Name())
+	}
+
+	providers[provider.Name()] = provider
+	providerOrder = append(providerOrder, provider.Name())
+}
+
+func GetProvider(name string) (EmoteProvider, bool) {
+	provider, exists := providers[name]
+	return provider, exists
+}
+
+// Names of all registered providers in registration order
+func ProviderNames() []string {
+	return append([]string{}, providerOrder...)
+}
+
+func channelKey(platform string, platformId string) string {
+	return platform + "-" + platformId
+}
+
+// Keeps loaded emotes by name for a provider. Embedded by providers so they only need to
+// implement loading.
+type emoteStore struct {
+	lock     sync.RWMutex
+	global   map[string]Emote
+	channels map[string]map[string]Emote
+}
+
+func (s *emoteStore) setGlobal(emotes []Emote) {
+	byName := make(map[string]Emote, len(emotes))
+	for _, emote := range emotes {
+		emote.Scope = ScopeGlobal
+		byName[emote.Name] = emote
+	}
+
+	s.lock.Lock()
+	s.global = byName
+	s.lock.Unlock()
+}
+
+func (s *emoteStore) setChannel(platform string, platformId string, emotes []Emote) {
+	byName := make(map[string]Emote, len(emotes))
+	for _, emote := range emotes {
+		emote.Scope = ScopeChannel
+		byName[emote.Name] = emote
+	}
+
+	s.lock.Lock()
+	if s.channels == nil {
+		s.channels = make(map[string]map[string]Emote)
+	}
+	s.channels[channelKey(platform, platformId)] = byName
+	s.lock.Unlock()
+}
+
+func (s *emoteStore) Lookup(platform string, platformId string, name string) (Emote, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if emote, exists := s.channels[channelKey(platform, platformId)][name]; exists {
+		return emote, true
+	}
+
+	emote, exists := s.global[name]
+	return emote, exists
+}
+
+func (s *emoteStore) Emotes(scope Scope, platform string, platformId string) []Emote {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	byName := s.global
+	if scope == ScopeChannel {
+		byName = s.channels[channelKey(platform, platformId)]
+	}
+
+	emotes := make([]Emote, 0, len(byName))
+	for _, emote := range byName {
+		emotes = append(emotes, emote)
+	}
+
+	return emotes
+}
@@ -0,0 +1,369 @@
+package emotes
+
+import (
+	"breakfast/services/apis"
+	"breakfast/services/events/types"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/patrickmn/go-cache"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// Largest emote image the proxy will store
+const maxProxiedImageSize = 10 << 20
+
+// Most the proxy will store in total, the oldest images are removed to make room
+const maxProxiedStorage = 1 << 30
+
+// Where proxied images are kept in the pocketbase filesystem
+const proxyStoragePrefix = "emotes/"
+
+var ErrUnknownEmote = errors.New("emote isn't loaded or hasn't been sent in chat")
+
+var proxyIdPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Sizes are the scale followed by the theme and format, e.g. 2x-dark.png or 2x.webp
+var proxySizePattern = regexp.MustCompile(`^([1-4])x(?:-(light|dark))?(?:\.(webp|avif|gif|png))?$`)
+
+var proxyImages bool = false
+var proxyImagesLock sync.RWMutex
+
+// Emotes that were given proxied images, only these and loaded emotes are fetched
+var proxiedEmotes *cache.Cache = cache.New(24*time.Hour, time.Hour)
+
+// Total size of the stored images, -1 until it's counted on the first fetch
+var proxyStoredSize int64 = -1
+var proxyStorageLock sync.Mutex
+
+// Images being fetched, so concurrent requests for the same image only fetch it once
+var proxyFetching map[string]*sync.Mutex = make(map[string]*sync.Mutex)
+var proxyFetchingLock sync.Mutex
+
+func GetProxyImages() bool {
+	proxyImagesLock.RLock()
+	defer proxyImagesLock.RUnlock()
+
+	return proxyImages
+}
+
+func setProxyImages(enabled bool) {
+	proxyImagesLock.Lock()
+	proxyImages = enabled
+	proxyImagesLock.Unlock()
+}
+
+// Proxy path of an emote image, the theme is part of the size so each theme is stored apart
+func ProxiedImagePath(provider string, id string, image types.ChatMessageImage) string {
+	size := strconv.Itoa(int(image.Scale)) + "x"
+	if image.Theme != "" {
+		size += "-" + image.Theme
+	}
+	if image.Format != "" {
+		size += "." + image.Format
+	}
+
+	return "/api/breakfast/emotes/image/" + provider + "/" + id + "/" + size
+}
+
+// Points images at the emote image proxy when it's enabled
+func proxyEmoteImages(provider string, id string, images []types.ChatMessageImage) []types.ChatMessageImage {
+	if !GetProxyImages() {
+		return images
+	}
+
+	proxied := make([]types.ChatMessageImage, 0, len(images))
+	for _, image := range images {
+		if image.Scale == 0 {
+			proxied = append(proxied, image)
+			continue
+		}
+
+		image.Url = ProxiedImagePath(provider, id, image)
+		proxied = append(proxied, image)
+	}
+
+	proxiedEmotes.SetDefault(provider+"/"+id, true)
+
+	return proxied
+}
+
+// Gets the cdn url of an emote image for the proxy
+func upstreamImageUrl(provider string, id string, scale int, theme string, format string) (string, error) {
+	switch provider {
+	case ProviderTwitch:
+		scales := map[int]string{1: "1.0", 2: "2.0", 4: "3.0"}
+		if _, exists := scales[scale]; !exists {
+			return "", errors.New("twitch emotes don't have scale " + strconv.Itoa(scale))
+		}
+
+		if theme == "" {
+			theme = "dark"
+		}
+
+		variant := "static"
+		if format == types.ImageFormatGif {
+			variant = "animated"
+		}
+
+		return "https://static-cdn.jtvnw.net/emoticons/v2/" + id + "/" + variant + "/" + theme + "/" + scales[scale], nil
+	case ProviderSTV:
+		if format == "" {
+			format = types.ImageFormatWebp
+		}
+
+		return "https://cdn.7tv.app/emote/" + id + "/" + strconv.Itoa(scale) + "x." + format, nil
+	case ProviderBTTV:
+		if scale > 3 {
+			return "", errors.New("bttv emotes don't have scale " + strconv.Itoa(scale))
+		}
+
+		return "https://cdn.betterttv.net/emote/" + id + "/" + strconv.Itoa(scale) + "x", nil
+	case ProviderFFZ:
+		if scale == 3 {
+			return "", errors.New("ffz emotes don't have scale 3")
+		}
+
+		// Animated ffz emotes are webp
+		if format == types.ImageFormatWebp {
+			return "https://cdn.frankerfacez.com/emote/" + id + "/animated/" + strconv.Itoa(scale), nil
+		}
+
+		return "https://cdn.frankerfacez.com/emote/" + id + "/" + strconv.Itoa(scale), nil
+	}
+
+	return "", errors.New("unknown emote provider: " + provider)
+}
+
+// Checks an emote is one the proxy handed out or is loaded for a channel, so the proxy can't be
+// used to store images of any id
+func isKnownEmote(provider string, id string) bool {
+	if _, exists := proxiedEmotes.Get(provider + "/" + id); exists {
+		return true
+	}
+
+	matches := func(emote Emote) bool {
+		return emote.Provider == provider && emote.Id == id
+	}
+
+	if provider == ProviderTwitch {
+		inSet := func(set *apis.TwitchEmoteSet) bool {
+			if set == nil {
+				return false
+			}
+
+			for _, emote := range set.Data {
+				if emote.Id == id {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		twitchChannelEmotesLock.RLock()
+		defer twitchChannelEmotesLock.RUnlock()
+
+		for _, set := range twitchChannelEmotes {
+			if inSet(set) {
+				return true
+			}
+		}
+
+		return inSet(twitchGlobals)
+	}
+
+	indexLock.RLock()
+	defer indexLock.RUnlock()
+
+	for _, emote := range globalIndex {
+		if matches(emote) {
+			return true
+		}
+	}
+
+	for _, index := range channelIndexes {
+		for _, emote := range index {
+			if matches(emote) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Makes room for an image in the proxy's storage, removing the oldest images once the total
+// would go over maxProxiedStorage
+func reserveProxyStorage(fs *filesystem.System, size int64) error {
+	proxyStorageLock.Lock()
+	defer proxyStorageLock.Unlock()
+
+	if proxyStoredSize < 0 {
+		stored, err := fs.List(proxyStoragePrefix)
+		if err != nil {
+			return err
+		}
+
+		proxyStoredSize = 0
+		for _, object := range stored {
+			proxyStoredSize += object.Size
+		}
+	}
+
+	if proxyStoredSize+size > maxProxiedStorage {
+		stored, err := fs.List(proxyStoragePrefix)
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(stored, func(i, j int) bool {
+			return stored[i].ModTime.Before(stored[j].ModTime)
+		})
+
+		// Clear down to three quarters so eviction doesn't run on every fetch
+		for _, object := range stored {
+			if proxyStoredSize+size <= maxProxiedStorage/4*3 {
+				break
+			}
+
+			err := fs.Delete(object.Key)
+			if err != nil {
+				return err
+			}
+
+			proxyStoredSize -= object.Size
+		}
+	}
+
+	proxyStoredSize += size
+
+	return nil
+}
+
+func fetchImage(url string) ([]byte, error) {
+	resp, err := http.DefaultClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, errors.New("request returned non success status: " + resp.Status)
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return nil, errors.New("request didn't return an image")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProxiedImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxProxiedImageSize {
+		return nil, errors.New("image is too large")
+	}
+
+	return data, nil
+}
+
+func registerProxyAPIs(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		// Public so overlays can load images without auth, only emotes the server knows of are
+		// fetched from their cdns
+		e.Router.GET("/api/breakfast/emotes/image/:provider/:id/:size", func(c echo.Context) error {
+			provider := c.PathParam("provider")
+			id := c.PathParam("id")
+			size := c.PathParam("size")
+
+			if !proxyIdPattern.MatchString(id) {
+				return c.JSON(http.StatusBadRequest, map[string]string{"message": "Bad emote id"})
+			}
+
+			match := proxySizePattern.FindStringSubmatch(size)
+			if match == nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"message": "Bad size"})
+			}
+			scale, _ := strconv.Atoi(match[1])
+			theme := match[2]
+			format := match[3]
+
+			upstream, err := upstreamImageUrl(provider, id, scale, theme, format)
+			if err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"message": "Bad image", "error": err.Error()})
+			}
+
+			fs, err := app.NewFilesystem()
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to open filesystem", "error": err.Error()})
+			}
+			defer fs.Close()
+
+			key := proxyStoragePrefix + provider + "/" + id + "/" + size
+
+			proxyFetchingLock.Lock()
+			fetching, exists := proxyFetching[key]
+			if !exists {
+				fetching = &sync.Mutex{}
+				proxyFetching[key] = fetching
+			}
+			proxyFetchingLock.Unlock()
+
+			fetching.Lock()
+			stored, err := fs.Exists(key)
+			if err == nil && !stored {
+				if !GetProxyImages() || !isKnownEmote(provider, id) {
+					err = ErrUnknownEmote
+				}
+
+				var data []byte
+				if err == nil {
+					data, err = fetchImage(upstream)
+				}
+				if err == nil {
+					err = reserveProxyStorage(fs, int64(len(data)))
+				}
+				if err == nil {
+					err = fs.Upload(data, key)
+				}
+			}
+			fetching.Unlock()
+
+			proxyFetchingLock.Lock()
+			delete(proxyFetching, key)
+			proxyFetchingLock.Unlock()
+
+			if errors.Is(err, ErrUnknownEmote) {
+				return c.JSON(http.StatusNotFound, map[string]string{"message": "Emote not found"})
+			}
+			if err != nil {
+				return c.JSON(http.StatusBadGateway, map[string]string{"message": "Failed to get image", "error": err.Error()})
+			}
+
+			// Emote images never change for an id and size
+			c.Response().Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+
+			{
+				err := fs.Serve(c.Response(), c.Request(), key, id+"-"+size)
+				if err != nil {
+					return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Failed to serve image", "error": err.Error()})
+				}
+			}
+
+			return nil
+		})
+
+		return nil
+	})
+}
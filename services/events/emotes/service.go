@@ -1,23 +1,85 @@
 package emotes
 
 import (
+	"breakfast/services/events/channels"
+	"breakfast/services/events/listener"
+
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/cron"
 )
 
 func RegisterService(app *pocketbase.PocketBase) {
+	registerSettingsAPIs(app)
+	registerProxyAPIs(app)
+	registerEmoteAPIs(app)
+
+	listener.OnEvent(HandleUsageEvent)
+
 	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		RefreshSTVGlobalEmotes()
-		GetAllSTVEmotesForTwitch()
+		RefreshGlobalEmotes()
+		RefreshAllChannelEmotes()
+
+		err := RefreshTwitchGlobalEmotes()
+		if err != nil {
+			app.Logger().Error("EMOTES Failed to get twitch global emotes", "error", err.Error())
+		}
+		GetAllTwitchEmotes()
+		GetAllTwitchCheermotes()
+
+		// Live emote set updates, sets are subscribed as channel emotes load
+		{
+			err := ConnectSTVEvents()
+			if err != nil {
+				app.Logger().Error("EMOTES Failed to connect to 7tv eventapi", "error", err.Error())
+				go reconnectSTVEvents()
+			}
+		}
 
 		return nil
 	})
+
+	app.OnTerminate().Add(func(e *core.TerminateEvent) error {
+		DisconnectSTVEvents()
+		return nil
+	})
+
+	// Load emotes for channels as they start being watched
+	channels.OnWatch(func(channel channels.WatchedChannel) {
+		RefreshChannelEmotes(channel.Provider, channel.ProviderId)
+
+		if channel.Provider != "twitch" {
+			return
+		}
+
+		{
+			err := RefreshTwitchChannelEmotes(channel.ProviderId)
+			if err != nil {
+				app.Logger().Error(
+					"EMOTES Failed to get twitch emotes for channel",
+					"twitchId", channel.ProviderId,
+					"error", err.Error(),
+				)
+			}
+		}
+
+		{
+			err := RefreshTwitchCheermotes(channel.ProviderId)
+			if err != nil {
+				app.Logger().Error(
+					"EMOTES Failed to get twitch cheermotes for channel",
+					"twitchId", channel.ProviderId,
+					"error", err.Error(),
+				)
+			}
+		}
+	})
 }
 
 func RegisterJobs(app *pocketbase.PocketBase, scheduler *cron.Cron) {
 	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
-		ScheduleSTVEmoteRefresh(app, scheduler)
+		ScheduleEmoteRefresh(app, scheduler)
+		ScheduleTwitchEmoteRefresh(app, scheduler)
 
 		return nil
 	})
@@ -0,0 +1,223 @@
+package emotes
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var DefaultProviderPrecedence = []string{ProviderSTV, ProviderBTTV, ProviderFFZ}
+
+var providerPrecedence []string
+var providerPrecedenceLock sync.RWMutex
+
+func GetProviderPrecedence() []string {
+	providerPrecedenceLock.RLock()
+	defer providerPrecedenceLock.RUnlock()
+
+	if providerPrecedence == nil {
+		return normalizePrecedence(DefaultProviderPrecedence)
+	}
+
+	return providerPrecedence
+}
+
+// Keeps registered providers in the given order and appends any left out so every provider is still used
+func normalizePrecedence(precedence []string) []string {
+	registered := ProviderNames()
+
+	normalized := []string{}
+	for _, provider := range precedence {
+		provider = strings.TrimSpace(provider)
+		if !slices.Contains(registered, provider) || slices.Contains(normalized, provider) {
+			continue
+		}
+
+		normalized = append(normalized, provider)
+	}
+
+	for _, provider := range registered {
+		if !slices.Contains(normalized, provider) {
+			normalized = append(normalized, provider)
+		}
+	}
+
+	return normalized
+}
+
+func setProviderPrecedence(precedence []string) {
+	providerPrecedenceLock.Lock()
+	providerPrecedence = precedence
+	providerPrecedenceLock.Unlock()
+
+	rebuildIndexes()
+}
+
+func registerSettingsAPIs(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		var query struct {
+			Value string `db:"value"`
+		}
+		err := app.Dao().DB().
+			Select("value").
+			From("_params").
+			Where(dbx.NewExp("key = 'breakfast-emotes-provider-precedence'")).
+			One(&query)
+		if err != nil {
+			app.Logger().Error("EMOTES Failed to load provider precedence from db", "error", err.Error())
+		} else {
+			setProviderPrecedence(normalizePrecedence(strings.Split(query.Value, ",")))
+		}
+
+		{
+			var query struct {
+				Value string `db:"value"`
+			}
+			err := app.Dao().DB().
+				Select("value").
+				From("_params").
+				Where(dbx.NewExp("key = 'breakfast-emotes-proxy-images'")).
+				One(&query)
+			if err != nil {
+				app.Logger().Error("EMOTES Failed to load image proxy setting from db", "error", err.Error())
+			} else {
+				setProxyImages(query.Value == "true")
+			}
+		}
+
+		e.Router.GET("/api/breakfast/emotes/settings/precedence", func(c echo.Context) error {
+			info := apis.RequestInfo(c)
+			user := info.AuthRecord
+
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			if user.Collection().Id != "users" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			return c.JSON(200, map[string][]string{
+				"precedence": GetProviderPrecedence(),
+				"available":  ProviderNames(),
+			})
+		})
+
+		e.Router.POST("/api/breakfast/emotes/settings/precedence", func(c echo.Context) error {
+			info := apis.RequestInfo(c)
+			user := info.AuthRecord
+
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			if user.Collection().Id != "users" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			var saved struct {
+				Precedence []string `json:"precedence"`
+			}
+
+			{
+				err := c.Bind(&saved)
+				if err != nil {
+					return c.JSON(400, map[string]string{"message": "Bad request"})
+				}
+			}
+
+			precedence := normalizePrecedence(saved.Precedence)
+
+			{
+				_, err := app.Dao().DB().
+					Update(
+						"_params",
+						dbx.Params{"value": strings.Join(precedence, ",")},
+						dbx.NewExp("key = 'breakfast-emotes-provider-precedence'"),
+					).
+					Execute()
+				if err != nil {
+					return c.JSON(500, map[string]string{"message": "Failed to saved settings", "error": err.Error()})
+				}
+			}
+
+			// Update memory cache to save having to query the database for every chat message
+			setProviderPrecedence(precedence)
+
+			return c.JSON(200, map[string]any{
+				"message":    "OK",
+				"precedence": precedence,
+			})
+		})
+
+		e.Router.GET("/api/breakfast/emotes/settings/proxy", func(c echo.Context) error {
+			info := apis.RequestInfo(c)
+			user := info.AuthRecord
+
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			if user.Collection().Id != "users" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			return c.JSON(200, map[string]bool{
+				"enabled": GetProxyImages(),
+			})
+		})
+
+		e.Router.POST("/api/breakfast/emotes/settings/proxy", func(c echo.Context) error {
+			info := apis.RequestInfo(c)
+			user := info.AuthRecord
+
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			if user.Collection().Id != "users" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			var saved struct {
+				Enabled bool `json:"enabled"`
+			}
+
+			{
+				err := c.Bind(&saved)
+				if err != nil {
+					return c.JSON(400, map[string]string{"message": "Bad request"})
+				}
+			}
+
+			{
+				_, err := app.Dao().DB().
+					Update(
+						"_params",
+						dbx.Params{"value": strconv.FormatBool(saved.Enabled)},
+						dbx.NewExp("key = 'breakfast-emotes-proxy-images'"),
+					).
+					Execute()
+				if err != nil {
+					return c.JSON(500, map[string]string{"message": "Failed to saved settings", "error": err.Error()})
+				}
+			}
+
+			setProxyImages(saved.Enabled)
+
+			return c.JSON(200, map[string]string{
+				"message": "OK",
+			})
+		})
+
+		return nil
+	})
+}
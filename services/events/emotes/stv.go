@@ -2,22 +2,32 @@ package emotes
 
 import (
 	"breakfast/services"
-	"encoding/json"
-	"errors"
-	"io"
-	"net/http"
-
-	"github.com/pocketbase/dbx"
-	"github.com/pocketbase/pocketbase"
-	"github.com/pocketbase/pocketbase/tools/cron"
+	"breakfast/services/events/types"
+	"strconv"
+	"strings"
+	"sync"
 )
 
+const ProviderSTV = "7tv"
+
+// Flag on an emote in a set to use it as zero width
+const STVActiveEmoteFlagZeroWidth = 1 << 0
+
+// Flag on the emote itself marking it as zero width wherever it's used
+const STVEmoteFlagZeroWidth = 1 << 8
+
+/*
+Flags - flags of the emote in the set, see STVActiveEmoteFlagZeroWidth
+Data.Flags - flags of the emote itself, see STVEmoteFlagZeroWidth
+*/
 type STVEmote struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
-	Data struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Flags int    `json:"flags"`
+	Data  struct {
 		Id       string `json:"id"`
 		Name     string `json:"name"`
+		Flags    int    `json:"flags"`
 		Listed   bool   `json:"listed"`
 		Animated bool   `json:"animated"`
 		Host     struct {
@@ -51,127 +61,101 @@ type STVUserConnection struct {
 	EmoteSet      STVEmoteSet `json:"emote_set"`
 }
 
-var stvConnections map[string]STVUserConnection = make(map[string]STVUserConnection, 0)
-var stvGlobals *STVEmoteSet
+func (e STVEmote) IsZeroWidth() bool {
+	return e.Flags&STVActiveEmoteFlagZeroWidth != 0 || e.Data.Flags&STVEmoteFlagZeroWidth != 0
+}
 
-func RefreshSTVGlobalEmotes() error {
-	url := "https://7tv.io/v3/emote-sets/global"
-	resp, err := http.DefaultClient.Get(url)
-	if err != nil {
-		return err
+func (e STVEmote) Emote() Emote {
+	images := make([]types.ChatMessageImage, 0, len(e.Data.Host.Files))
+	for _, file := range e.Data.Host.Files {
+		// Files are named by scale, e.g. 2x.webp
+		scale, _ := strconv.ParseFloat(strings.SplitN(file.Name, "x", 2)[0], 64)
+
+		images = append(images, types.ChatMessageImage{
+			Url:      e.Data.Host.Url + "/" + file.Name,
+			Width:    file.Width,
+			Height:   file.Height,
+			Scale:    scale,
+			Format:   strings.ToLower(file.Format),
+			Animated: file.FrameCount > 1,
+		})
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return errors.New("request returned non success status: " + resp.Status)
+	return Emote{
+		Id:        e.Id,
+		Name:      e.Name,
+		Provider:  ProviderSTV,
+		Images:    images,
+		ZeroWidth: e.IsZeroWidth(),
 	}
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+func stvEmotes(set STVEmoteSet) []Emote {
+	emotes := make([]Emote, 0, len(set.Emotes))
+	for _, emote := range set.Emotes {
+		emotes = append(emotes, emote.Emote())
 	}
 
-	var set STVEmoteSet
-	{
-		err := json.Unmarshal(body, &set)
-		if err != nil {
-			return err
-		}
-	}
+	return emotes
+}
 
-	stvGlobals = &set
+// The user connections are kept alongside the emotes for the emote set ids
+var stvConnections map[string]STVUserConnection = make(map[string]STVUserConnection, 0)
+var stvConnectionsLock sync.RWMutex
 
-	return nil
+type stvProvider struct {
+	emoteStore
+}
+
+func init() {
+	RegisterProvider(&stvProvider{})
 }
 
-func RefreshSTVEmotes(provider string, providerId string) error {
-	services.App.Logger().Debug(
-		"EMOTES Refreshing emotes",
-		"provider", provider,
-		"providerId", providerId,
-	)
+func (p *stvProvider) Name() string {
+	return ProviderSTV
+}
 
-	url := "https://7tv.io/v3/users/" + provider + "/" + providerId
-	resp, err := http.DefaultClient.Get(url)
+func (p *stvProvider) LoadGlobal() error {
+	var set STVEmoteSet
+	_, err := getJSON("https://7tv.io/v3/emote-sets/global", &set)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == 404 {
-		services.App.Logger().Debug(
-			"EMOTES User does not have stv emotes (or bad url)",
-			"provider", provider,
-			"providerId", providerId,
-		)
-		return nil
-	}
+	p.setGlobal(stvEmotes(set))
 
-	if resp.StatusCode != 200 {
-		return errors.New("request returned non success status: " + resp.Status)
-	}
+	return nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+func (p *stvProvider) LoadChannel(platform string, platformId string) error {
+	var connection STVUserConnection
+	found, err := getJSON("https://7tv.io/v3/users/"+platform+"/"+platformId, &connection)
 	if err != nil {
 		return err
 	}
 
-	var connection STVUserConnection
-	{
-		err := json.Unmarshal(body, &connection)
-		if err != nil {
-			return err
-		}
-	}
-
-	stvConnections[provider+"-"+providerId] = connection
-	services.App.Logger().Debug(
-		"EMOTES Refreshed emotes for user",
-		"provider", provider,
-		"providerId", providerId,
-	)
+	if !found {
+		services.App.Logger().Debug(
+			"EMOTES User does not have stv emotes (or bad url)",
+			"provider", platform,
+			"providerId", platformId,
+		)
 
-	return nil
-}
+		// Clear emotes the channel had before they were removed
+		stvConnectionsLock.Lock()
+		delete(stvConnections, channelKey(platform, platformId))
+		stvConnectionsLock.Unlock()
 
-func GetAllSTVEmotesForTwitch() error {
-	var query []struct {
-		UserId string `db:"userId"`
-	}
-	{
-		err := services.App.Dao().DB().
-			Select("json_extract(tes.config, '$.condition.broadcaster_user_id') as userId").
-			Distinct(true).
-			From("twitch_event_subscriptions as tes").
-			Where(dbx.NewExp("userId IS NOT NULL")).
-			All(&query)
-		if err != nil {
-			services.App.Logger().Error(
-				"EMOTES Failed to get distinct twitch subscription users",
-				"error", err.Error(),
-			)
-			return err
-		}
+		p.setChannel(platform, platformId, nil)
+		return nil
 	}
 
-	for _, user := range query {
-		err := RefreshSTVEmotes("twitch", user.UserId)
-		if err != nil {
-			services.App.Logger().Error(
-				"EMOTES Failed to get emotes for twitch user",
-				"twitchId", user.UserId,
-				"error", err.Error(),
-			)
-			continue
-		}
-	}
+	stvConnectionsLock.Lock()
+	stvConnections[channelKey(platform, platformId)] = connection
+	stvConnectionsLock.Unlock()
 
-	return nil
-}
+	p.setChannel(platform, platformId, stvEmotes(connection.EmoteSet))
+	trackSTVEmoteSet(platform, platformId, connection.EmoteSet.Id)
 
-func ScheduleSTVEmoteRefresh(app *pocketbase.PocketBase, scheduler *cron.Cron) {
-	scheduler.MustAdd("emotes-refresh-stv", "0 */6 * * *", func() {
-		RefreshSTVGlobalEmotes()
-		GetAllSTVEmotesForTwitch()
-	})
+	return nil
 }
@@ -0,0 +1,351 @@
+package emotes
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/channels"
+	"breakfast/services/events/listener"
+	"breakfast/services/events/types"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const STVEventsWsUrl = "wss://events.7tv.io/v3"
+
+// 7tv eventapi opcodes, see https://github.com/SevenTV/EventAPI
+const (
+	stvOpDispatch    = 0
+	stvOpHello       = 1
+	stvOpHeartbeat   = 2
+	stvOpReconnect   = 4
+	stvOpAck         = 5
+	stvOpError       = 6
+	stvOpEndOfStream = 7
+	stvOpSubscribe   = 35
+	stvOpUnsubscribe = 36
+)
+
+type stvEventMessage struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+}
+
+type stvEventHello struct {
+	HeartbeatInterval int    `json:"heartbeat_interval"`
+	SessionId         string `json:"session_id"`
+}
+
+type stvEventDispatch struct {
+	Type string `json:"type"`
+	Body struct {
+		Id      string           `json:"id"`
+		Pushed  []stvChangeField `json:"pushed"`
+		Pulled  []stvChangeField `json:"pulled"`
+		Updated []stvChangeField `json:"updated"`
+	} `json:"body"`
+}
+
+type stvChangeField struct {
+	Key      string    `json:"key"`
+	Index    *int      `json:"index"`
+	OldValue *STVEmote `json:"old_value"`
+	Value    *STVEmote `json:"value"`
+}
+
+var stvEventsWs *websocket.Conn
+var stvEventsLock sync.Mutex
+var stvEventsShutdown bool = false
+
+// Emote set ids subscribed to, mapped to the channel key the set belongs to
+var stvSubscribedSets map[string]string = make(map[string]string)
+var stvSubscribedSetsLock sync.RWMutex
+
+func stvEventsSend(op int, data any) error {
+	stvEventsLock.Lock()
+	defer stvEventsLock.Unlock()
+
+	if stvEventsWs == nil {
+		return errors.New("7tv eventapi is not connected")
+	}
+
+	return stvEventsWs.WriteJSON(map[string]any{"op": op, "d": data})
+}
+
+func stvEmoteSetCondition(op int, setId string) error {
+	return stvEventsSend(op, map[string]any{
+		"type":      "emote_set.update",
+		"condition": map[string]string{"object_id": setId},
+	})
+}
+
+// Tracks the emote set of a channel for live updates, replacing the channel's previous set
+func trackSTVEmoteSet(platform string, platformId string, setId string) {
+	key := channelKey(platform, platformId)
+
+	stvSubscribedSetsLock.Lock()
+	previous := ""
+	for id, channel := range stvSubscribedSets {
+		if channel == key {
+			previous = id
+		}
+	}
+	if previous == setId {
+		stvSubscribedSetsLock.Unlock()
+		return
+	}
+	if previous != "" {
+		delete(stvSubscribedSets, previous)
+	}
+	if setId != "" {
+		stvSubscribedSets[setId] = key
+	}
+	stvSubscribedSetsLock.Unlock()
+
+	// When not connected the sets are subscribed once the connection says hello
+	if previous != "" {
+		stvEmoteSetCondition(stvOpUnsubscribe, previous)
+	}
+	if setId != "" {
+		stvEmoteSetCondition(stvOpSubscribe, setId)
+	}
+}
+
+func DisconnectSTVEvents() {
+	stvEventsLock.Lock()
+	defer stvEventsLock.Unlock()
+
+	stvEventsShutdown = true
+	if stvEventsWs == nil {
+		return
+	}
+
+	stvEventsWs.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	stvEventsWs.Close()
+	stvEventsWs = nil
+}
+
+// Retries connecting to the 7tv eventapi with a back off so an outage doesn't turn into a reconnect loop
+func reconnectSTVEvents() {
+	for attempt := 1; ; attempt++ {
+		time.Sleep(time.Duration(min(attempt*5, 60)) * time.Second)
+
+		stvEventsLock.Lock()
+		shutdown := stvEventsShutdown
+		stvEventsLock.Unlock()
+		if shutdown {
+			return
+		}
+
+		err := ConnectSTVEvents()
+		if err == nil {
+			return
+		}
+
+		services.App.Logger().Error("EMOTES 7tv eventapi failed to reconnect", "error", err.Error())
+	}
+}
+
+// Connects to the 7tv eventapi and keeps the connection alive, reconnecting whenever it drops
+func ConnectSTVEvents() error {
+	socket, _, err := websocket.DefaultDialer.Dial(STVEventsWsUrl, nil)
+	if err != nil {
+		return err
+	}
+
+	stvEventsLock.Lock()
+	stvEventsShutdown = false
+	stvEventsWs = socket
+	stvEventsLock.Unlock()
+
+	services.App.Logger().Debug("EMOTES 7tv eventapi connected")
+
+	go func() {
+		// Until hello gives the real interval
+		heartbeatTimeout := time.Minute
+
+		for {
+			socket.SetReadDeadline(time.Now().Add(heartbeatTimeout))
+
+			var message stvEventMessage
+			err := socket.ReadJSON(&message)
+			if err != nil {
+				services.App.Logger().Debug("EMOTES 7tv eventapi read failed", "error", err.Error())
+				break
+			}
+
+			switch message.Op {
+			case stvOpHello:
+				var hello stvEventHello
+				json.Unmarshal(message.D, &hello)
+				if hello.HeartbeatInterval > 0 {
+					// Allow a few missed heartbeats before treating the connection as dead
+					heartbeatTimeout = time.Duration(hello.HeartbeatInterval*3) * time.Millisecond
+				}
+
+				stvSubscribedSetsLock.RLock()
+				sets := make([]string, 0, len(stvSubscribedSets))
+				for id := range stvSubscribedSets {
+					sets = append(sets, id)
+				}
+				stvSubscribedSetsLock.RUnlock()
+
+				for _, id := range sets {
+					err := stvEmoteSetCondition(stvOpSubscribe, id)
+					if err != nil {
+						services.App.Logger().Error(
+							"EMOTES 7tv eventapi failed to subscribe to emote set",
+							"emoteSetId", id,
+							"error", err.Error(),
+						)
+					}
+				}
+			case stvOpDispatch:
+				var dispatch stvEventDispatch
+				{
+					err := json.Unmarshal(message.D, &dispatch)
+					if err != nil {
+						services.App.Logger().Error(
+							"EMOTES 7tv eventapi sent a bad dispatch",
+							"error", err.Error(),
+						)
+						continue
+					}
+				}
+
+				if dispatch.Type == "emote_set.update" {
+					applySTVEmoteSetUpdate(&dispatch)
+				}
+			case stvOpError:
+				services.App.Logger().Error("EMOTES 7tv eventapi sent an error", "data", string(message.D))
+			case stvOpReconnect, stvOpEndOfStream:
+				socket.Close()
+			}
+		}
+
+		socket.Close()
+
+		stvEventsLock.Lock()
+		if stvEventsWs == socket {
+			stvEventsWs = nil
+		}
+		shutdown := stvEventsShutdown
+		stvEventsLock.Unlock()
+
+		if shutdown {
+			return
+		}
+
+		reconnectSTVEvents()
+	}()
+
+	return nil
+}
+
+// Patches the in memory emote set of the channel and emits an emotes-updated event
+func applySTVEmoteSetUpdate(dispatch *stvEventDispatch) {
+	stvSubscribedSetsLock.RLock()
+	key, exists := stvSubscribedSets[dispatch.Body.Id]
+	stvSubscribedSetsLock.RUnlock()
+	if !exists {
+		return
+	}
+
+	platform, platformId, _ := strings.Cut(key, "-")
+
+	added := []string{}
+	removed := []string{}
+
+	stvConnectionsLock.Lock()
+	connection, exists := stvConnections[key]
+	if !exists || connection.EmoteSet.Id != dispatch.Body.Id {
+		stvConnectionsLock.Unlock()
+		return
+	}
+
+	// Copied so readers of the previous set never see it change
+	emotes := slices.Clone(connection.EmoteSet.Emotes)
+	for _, change := range dispatch.Body.Pulled {
+		if change.Key != "emotes" || change.OldValue == nil {
+			continue
+		}
+
+		for i, emote := range emotes {
+			if emote.Id == change.OldValue.Id {
+				emotes = append(emotes[:i:i], emotes[i+1:]...)
+				break
+			}
+		}
+		removed = append(removed, change.OldValue.Name)
+	}
+
+	for _, change := range dispatch.Body.Updated {
+		if change.Key != "emotes" || change.Value == nil {
+			continue
+		}
+
+		for i, emote := range emotes {
+			if emote.Id != change.Value.Id {
+				continue
+			}
+
+			if emote.Name != change.Value.Name {
+				removed = append(removed, emote.Name)
+				added = append(added, change.Value.Name)
+			}
+
+			// Updates don't always include the emote data
+			if change.Value.Data.Id == "" {
+				change.Value.Data = emote.Data
+			}
+			emotes[i] = *change.Value
+			break
+		}
+	}
+
+	for _, change := range dispatch.Body.Pushed {
+		if change.Key != "emotes" || change.Value == nil {
+			continue
+		}
+
+		emotes = append(emotes, *change.Value)
+		added = append(added, change.Value.Name)
+	}
+
+	connection.EmoteSet.Emotes = emotes
+	stvConnections[key] = connection
+	stvConnectionsLock.Unlock()
+
+	if provider, exists := GetProvider(ProviderSTV); exists {
+		provider.(*stvProvider).setChannel(platform, platformId, stvEmotes(connection.EmoteSet))
+	}
+	rebuildChannelIndex(platform, platformId)
+
+	services.App.Logger().Debug(
+		"EMOTES 7tv emote set updated",
+		"provider", platform,
+		"providerId", platformId,
+		"added", added,
+		"removed", removed,
+	)
+
+	channel := types.Channel{Id: platformId, Platform: platform}
+	if watched, exists := channels.Get(platform, platformId); exists {
+		channel = watched.Channel()
+	}
+
+	listener.EmitEvent(ProviderSTV, dispatch.Body.Id, types.BreakfastEvent{
+		Type:     types.EventTypeEmotesUpdated,
+		Platform: platform,
+		Data: &types.EmotesUpdated{
+			Channel:  channel,
+			Provider: ProviderSTV,
+			Added:    added,
+			Removed:  removed,
+		},
+	})
+}
@@ -1 +1,148 @@
 package emotes
+
+import (
+	"breakfast/services"
+	"breakfast/services/apis"
+	"breakfast/services/events/channels"
+	"breakfast/services/events/types"
+	"sync"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/cron"
+)
+
+const ProviderTwitch = "twitch"
+
+// Twitch emotes are already parsed into fragments by twitch, these are kept so the
+// emotes available in each watched channel are known
+var twitchChannelEmotes map[string]*apis.TwitchEmoteSet = make(map[string]*apis.TwitchEmoteSet, 0)
+var twitchChannelEmotesLock sync.RWMutex
+var twitchGlobals *apis.TwitchEmoteSet
+
+// Twitch emotes are 28px square at 1.0 scale
+var twitchEmoteScales = []struct {
+	Name  string
+	Scale float64
+}{
+	{"1.0", 1},
+	{"2.0", 2},
+	{"3.0", 4},
+}
+
+// Twitch serves every emote in both themes, used when an emote's themes aren't known like
+// emotes in chat messages
+var twitchEmoteThemes = []string{"light", "dark"}
+
+// Builds the cdn images of a twitch emote for each of its formats (static, animated) and
+// themes (light, dark). The default themes are used when none are given.
+func TwitchEmoteImages(emoteId string, formats []string, themes []string) []types.ChatMessageImage {
+	if len(themes) == 0 {
+		themes = twitchEmoteThemes
+	}
+
+	images := []types.ChatMessageImage{}
+	for _, theme := range themes {
+		for _, format := range formats {
+			animated := format == "animated"
+			imageFormat := types.ImageFormatPng
+			if animated {
+				imageFormat = types.ImageFormatGif
+			}
+
+			for _, scale := range twitchEmoteScales {
+				images = append(images, types.ChatMessageImage{
+					Url:      "https://static-cdn.jtvnw.net/emoticons/v2/" + emoteId + "/" + format + "/" + theme + "/" + scale.Name,
+					Width:    int(28 * scale.Scale),
+					Height:   int(28 * scale.Scale),
+					Scale:    scale.Scale,
+					Format:   imageFormat,
+					Animated: animated,
+					Theme:    theme,
+				})
+			}
+		}
+	}
+
+	return proxyEmoteImages(ProviderTwitch, emoteId, images)
+}
+
+func RefreshTwitchGlobalEmotes() error {
+	set, err := apis.GetTwitchGlobalEmotes()
+	if err != nil {
+		return err
+	}
+
+	twitchGlobals = set
+
+	return nil
+}
+
+func RefreshTwitchChannelEmotes(broadcasterId string) error {
+	services.App.Logger().Debug(
+		"EMOTES Refreshing twitch channel emotes",
+		"twitchId", broadcasterId,
+	)
+
+	set, err := apis.GetTwitchChannelEmotes(broadcasterId)
+	if err != nil {
+		return err
+	}
+
+	twitchChannelEmotesLock.Lock()
+	twitchChannelEmotes[broadcasterId] = set
+	twitchChannelEmotesLock.Unlock()
+
+	return nil
+}
+
+func GetAllTwitchEmotes() error {
+	for _, channel := range channels.AllForProvider("twitch") {
+		err := RefreshTwitchChannelEmotes(channel.ProviderId)
+		if err != nil {
+			services.App.Logger().Error(
+				"EMOTES Failed to get twitch emotes for channel",
+				"twitchId", channel.ProviderId,
+				"error", err.Error(),
+			)
+			continue
+		}
+	}
+
+	return nil
+}
+
+func twitchEmotes(set *apis.TwitchEmoteSet, scope Scope) []Emote {
+	if set == nil {
+		return []Emote{}
+	}
+
+	emotes := make([]Emote, 0, len(set.Data))
+	for _, emote := range set.Data {
+		emotes = append(emotes, Emote{
+			Id:       emote.Id,
+			Name:     emote.Name,
+			Provider: ProviderTwitch,
+			Scope:    scope,
+			Images:   TwitchEmoteImages(emote.Id, emote.Format, emote.ThemeMode),
+		})
+	}
+
+	return emotes
+}
+
+// The twitch emotes of a channel and the global twitch emotes
+func TwitchEmotes(broadcasterId string) []Emote {
+	twitchChannelEmotesLock.RLock()
+	channelSet := twitchChannelEmotes[broadcasterId]
+	twitchChannelEmotesLock.RUnlock()
+
+	return append(twitchEmotes(channelSet, ScopeChannel), twitchEmotes(twitchGlobals, ScopeGlobal)...)
+}
+
+func ScheduleTwitchEmoteRefresh(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	scheduler.MustAdd("emotes-refresh-twitch", "0 */6 * * *", func() {
+		RefreshTwitchGlobalEmotes()
+		GetAllTwitchEmotes()
+		GetAllTwitchCheermotes()
+	})
+}
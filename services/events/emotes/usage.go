@@ -0,0 +1,230 @@
+package emotes
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/channels"
+	"breakfast/services/events/streams"
+	"breakfast/services/events/types"
+	"errors"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+const UsageRangeToday = "today"
+const UsageRangeStream = "stream"
+const UsageRangeAll = "all"
+
+type usageKey struct {
+	Provider string
+	Id       string
+}
+
+/*
+Name - the emote's name when it was last used
+Images - the emote's images if it's still available in the channel
+*/
+type EmoteUsage struct {
+	Provider string                   `json:"provider" db:"provider"`
+	EmoteId  string                   `json:"emoteId" db:"emoteId"`
+	Name     string                   `json:"name" db:"name"`
+	Count    int                      `json:"count" db:"count"`
+	Images   []types.ChatMessageImage `json:"images" db:"-"`
+}
+
+// Counts the emotes in a message's fragments, including zero width emotes stacked on them
+func countEmotes(fragments []types.ChatMessageFragment, counts map[usageKey]int, names map[usageKey]string) {
+	for _, fragment := range fragments {
+		if fragment.Emote != nil {
+			key := usageKey{fragment.Emote.Provider, fragment.Emote.Id}
+			counts[key]++
+			names[key] = fragment.Text
+		}
+
+		countEmotes(fragment.Overlays, counts, names)
+	}
+}
+
+// Adds the emotes used in a chat message to the daily, stream session and viewer usage counts
+// of the channel it was received in. Messages in channels that aren't watched aren't counted.
+func RecordUsage(message *types.ChatMessage) error {
+	channel, exists := channels.Get(message.Channel.Platform, message.Channel.Id)
+	if !exists || channel.Id == "" {
+		return nil
+	}
+
+	counts := map[usageKey]int{}
+	names := map[usageKey]string{}
+	countEmotes(message.Fragments, counts, names)
+	if len(counts) == 0 {
+		return nil
+	}
+
+	session, live := streams.Current(channel.Id)
+	day := time.Now().UTC().Format(time.DateOnly)
+
+	return services.App.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		for key, count := range counts {
+			params := dbx.Params{
+				"day":      day,
+				"channel":  channel.Id,
+				"session":  session.Id,
+				"provider": key.Provider,
+				"emoteId":  key.Id,
+				"name":     names[key],
+				"count":    count,
+			}
+
+			{
+				_, err := txDao.DB().
+					NewQuery(`INSERT INTO emote_usage_daily (day, channel, provider, emoteId, name, count)
+						VALUES ({:day}, {:channel}, {:provider}, {:emoteId}, {:name}, {:count})
+						ON CONFLICT (day, channel, provider, emoteId)
+						DO UPDATE SET count = count + excluded.count, name = excluded.name`).
+					Bind(params).
+					Execute()
+				if err != nil {
+					return err
+				}
+			}
+
+			if live {
+				_, err := txDao.DB().
+					NewQuery(`INSERT INTO emote_usage_sessions (session, provider, emoteId, name, count)
+						VALUES ({:session}, {:provider}, {:emoteId}, {:name}, {:count})
+						ON CONFLICT (session, provider, emoteId)
+						DO UPDATE SET count = count + excluded.count, name = excluded.name`).
+					Bind(params).
+					Execute()
+				if err != nil {
+					return err
+				}
+			}
+
+			if message.Viewer != nil {
+				params["viewer"] = message.Viewer.Id
+
+				_, err := txDao.DB().
+					NewQuery(`INSERT INTO emote_usage_viewers (viewer, channel, provider, emoteId, name, count)
+						VALUES ({:viewer}, {:channel}, {:provider}, {:emoteId}, {:name}, {:count})
+						ON CONFLICT (viewer, channel, provider, emoteId)
+						DO UPDATE SET count = count + excluded.count, name = excluded.name`).
+					Bind(params).
+					Execute()
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Records emote usage of emitted chat messages, run by the listener outside of the event's path
+func HandleUsageEvent(provider string, providerId string, event types.BreakfastEvent) {
+	message, ok := event.Data.(*types.ChatMessage)
+	if !ok {
+		return
+	}
+
+	err := RecordUsage(message)
+	if err != nil {
+		services.App.Logger().Error(
+			"EMOTES Failed to record emote usage",
+			"channel", message.Channel.Id,
+			"error", err.Error(),
+		)
+	}
+}
+
+/*
+Channel - limits usage to a channel, all channels are counted together when nil
+Range - one of today, stream or all. Stream is the live session or the last one if offline
+Viewer - limits usage to a viewer, only supported for all time usage
+*/
+type UsageQuery struct {
+	Channel *channels.WatchedChannel
+	Range   string
+	Viewer  string
+	Limit   int
+}
+
+// Gets the most used emotes for a query, most used first
+func TopEmotes(query UsageQuery) ([]EmoteUsage, error) {
+	builder := services.App.Dao().DB().
+		Select("provider", "emoteId", "MAX(name) as name", "SUM(count) as count").
+		GroupBy("provider", "emoteId").
+		OrderBy("count DESC", "name ASC").
+		Limit(int64(query.Limit))
+
+	if query.Viewer != "" && query.Range != UsageRangeAll {
+		return nil, errors.New("viewer usage is only counted for all time")
+	}
+
+	switch query.Range {
+	case UsageRangeToday:
+		builder.From("emote_usage_daily").
+			Where(dbx.HashExp{"day": time.Now().UTC().Format(time.DateOnly)})
+		if query.Channel != nil {
+			builder.AndWhere(dbx.HashExp{"channel": query.Channel.Id})
+		}
+	case UsageRangeStream:
+		if query.Channel == nil {
+			return nil, errors.New("a channel is needed for stream usage")
+		}
+
+		session, live := streams.Current(query.Channel.Id)
+		if !live {
+			latest, err := streams.Latest(query.Channel.Id)
+			if err != nil {
+				return []EmoteUsage{}, nil
+			}
+			session = latest
+		}
+
+		builder.From("emote_usage_sessions").
+			Where(dbx.HashExp{"session": session.Id})
+	case UsageRangeAll:
+		if query.Viewer != "" {
+			builder.From("emote_usage_viewers").
+				Where(dbx.HashExp{"viewer": query.Viewer})
+		} else {
+			builder.From("emote_usage_daily")
+		}
+		if query.Channel != nil {
+			builder.AndWhere(dbx.HashExp{"channel": query.Channel.Id})
+		}
+	default:
+		return nil, errors.New("unknown usage range: " + query.Range)
+	}
+
+	usage := []EmoteUsage{}
+	{
+		err := builder.All(&usage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	platform, platformId := "", ""
+	if query.Channel != nil {
+		platform, platformId = query.Channel.Provider, query.Channel.ProviderId
+	}
+	index := getIndex(platform, platformId)
+
+	for i, emote := range usage {
+		usage[i].Images = []types.ChatMessageImage{}
+		if emote.Provider == ProviderTwitch {
+			usage[i].Images = TwitchEmoteImages(emote.EmoteId, []string{"static"}, nil)
+			continue
+		}
+
+		if indexed, exists := index[emote.Name]; exists && indexed.Id == emote.EmoteId {
+			usage[i].Images = proxyEmoteImages(indexed.Provider, indexed.Id, indexed.Images)
+		}
+	}
+
+	return usage, nil
+}
@@ -2,133 +2,118 @@ package emotes
 
 import (
 	"breakfast/services/events/types"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
 	"strings"
 )
 
-func EmotifyFragments(provider string, providerId string, fragments []types.ChatMessageFragment) []types.ChatMessageFragment {
-	emotifiedFragments := fragments
-
-	if stvGlobals != nil {
-		working := make([]types.ChatMessageFragment, 0)
+// Gets json from an emote provider api. Returns false if the resource doesn't exist.
+func getJSON(url string, out any) (bool, error) {
+	resp, err := http.DefaultClient.Get(url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
 
-		for _, fragment := range emotifiedFragments {
-			if fragment.Type != "text" {
-				working = append(working, fragment)
-				continue
-			}
+	if resp.StatusCode == 404 {
+		return false, nil
+	}
 
-			text := ""
-			words := strings.Split(fragment.Text, " ")
-			wordsLength := len(words)
-		STVGlobalWord:
-			for wordIdx, word := range words {
-				for _, emote := range stvGlobals.Emotes {
-					if emote.Data.Name == word {
-						if text != "" {
-							working = append(working, types.ChatMessageFragment{
-								Type:   "text",
-								Text:   text,
-								Images: []types.ChatMessageImage{},
-							})
-
-							text = ""
-							if wordIdx != wordsLength-1 {
-								text += " "
-							}
-						}
-
-						images := make([]types.ChatMessageImage, 0)
-						for _, file := range emote.Data.Host.Files {
-							images = append(images, types.ChatMessageImage{
-								Url: emote.Data.Host.Url + "/" + file.Name,
-							})
-						}
-						working = append(working, types.ChatMessageFragment{
-							Type:   "emote",
-							Text:   word,
-							Images: images,
-						})
-						continue STVGlobalWord
-					}
-				}
+	if resp.StatusCode != 200 {
+		return false, errors.New("request returned non success status: " + resp.Status)
+	}
 
-				text += word
-				if wordIdx != wordsLength-1 {
-					text += " "
-				}
-			}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
 
-			if text != "" {
-				working = append(working, types.ChatMessageFragment{
-					Type: "text",
-					Text: text,
-				})
-			}
+	{
+		err := json.Unmarshal(body, out)
+		if err != nil {
+			return false, err
 		}
+	}
+
+	return true, nil
+}
 
-		emotifiedFragments = working
+// Replaces emote names in text fragments with emote fragments. Each text fragment is split into
+// words once and each word is looked up in the channel's merged emote index. Zero width emotes
+// directly after an emote are folded into that emote's overlays.
+func EmotifyFragments(provider string, providerId string, fragments []types.ChatMessageFragment) []types.ChatMessageFragment {
+	index := getIndex(provider, providerId)
+	if len(index) == 0 {
+		return fragments
 	}
 
-	if stvConnection, exists := stvConnections[provider+"-"+providerId]; exists {
-		working := make([]types.ChatMessageFragment, 0)
+	working := make([]types.ChatMessageFragment, 0, len(fragments))
 
-		for _, fragment := range emotifiedFragments {
-			if fragment.Type != "text" {
-				working = append(working, fragment)
-				continue
-			}
+	for _, fragment := range fragments {
+		if fragment.Type != "text" {
+			working = append(working, fragment)
+			continue
+		}
 
-			text := ""
-			words := strings.Split(fragment.Text, " ")
-			wordsLength := len(words)
-		STVSetWord:
-			for wordIdx, word := range words {
-				for _, emote := range stvConnection.EmoteSet.Emotes {
-					if emote.Data.Name == word {
-						if text != "" {
-							working = append(working, types.ChatMessageFragment{
-								Type:   "text",
-								Text:   text,
-								Images: []types.ChatMessageImage{},
-							})
-
-							text = ""
-							if wordIdx != wordsLength-1 {
-								text += " "
-							}
-						}
-
-						images := make([]types.ChatMessageImage, 0)
-						for _, file := range emote.Data.Host.Files {
-							images = append(images, types.ChatMessageImage{
-								Url: emote.Data.Host.Url + "/" + file.Name,
-							})
-						}
-						working = append(working, types.ChatMessageFragment{
-							Type:   "emote",
-							Text:   word,
-							Images: images,
-						})
-						continue STVSetWord
+		text := ""
+		words := strings.Split(fragment.Text, " ")
+		wordsLength := len(words)
+		for wordIdx, word := range words {
+			if emote, found := index[word]; found {
+				// Only whitespace separates a zero width emote from the emote it goes on top of
+				last := len(working) - 1
+				if emote.ZeroWidth && strings.TrimSpace(text) == "" && last >= 0 && working[last].Type == "emote" {
+					working[last].Overlays = append(working[last].Overlays, types.ChatMessageFragment{
+						Type:   "emote",
+						Text:   word,
+						Images: proxyEmoteImages(emote.Provider, emote.Id, emote.Images),
+						Emote:  &types.ChatMessageEmote{Id: emote.Id, Provider: emote.Provider},
+					})
+
+					text = ""
+					if wordIdx != wordsLength-1 {
+						text += " "
 					}
+					continue
 				}
 
-				text += word
-				if wordIdx != wordsLength-1 {
-					text += " "
+				if text != "" {
+					working = append(working, types.ChatMessageFragment{
+						Type:   "text",
+						Text:   text,
+						Images: []types.ChatMessageImage{},
+					})
+
+					text = ""
+					if wordIdx != wordsLength-1 {
+						text += " "
+					}
 				}
-			}
 
-			if text != "" {
 				working = append(working, types.ChatMessageFragment{
-					Type: "text",
-					Text: text,
+					Type:   "emote",
+					Text:   word,
+					Images: proxyEmoteImages(emote.Provider, emote.Id, emote.Images),
+					Emote:  &types.ChatMessageEmote{Id: emote.Id, Provider: emote.Provider},
 				})
+				continue
+			}
+
+			text += word
+			if wordIdx != wordsLength-1 {
+				text += " "
 			}
 		}
 
-		emotifiedFragments = working
+		if text != "" {
+			working = append(working, types.ChatMessageFragment{
+				Type: "text",
+				Text: text,
+			})
+		}
 	}
 
-	return emotifiedFragments
+	return working
 }
@@ -17,6 +17,15 @@ import (
 var pb *pocketbase.PocketBase
 var SavedEventTypes []string
 
+// Called in the background with every emitted event
+type EventHook func(provider string, providerId string, event types.BreakfastEvent)
+
+var eventHooks []EventHook = []EventHook{}
+
+func OnEvent(hook EventHook) {
+	eventHooks = append(eventHooks, hook)
+}
+
 func SetupListener(app *pocketbase.PocketBase) {
 	pb = app
 
@@ -39,6 +48,29 @@ func SetupListener(app *pocketbase.PocketBase) {
 
 }
 
+// Gets the channel ids or usernames a client subscribed with through the "channel" query
+// option, either as a comma separated string or a list
+func subscriptionChannels(options subscriptions.SubscriptionOptions) []string {
+	channels := []string{}
+
+	switch value := options.Query["channel"].(type) {
+	case string:
+		for _, channel := range strings.Split(value, ",") {
+			if channel = strings.TrimSpace(channel); channel != "" {
+				channels = append(channels, channel)
+			}
+		}
+	case []any:
+		for _, channel := range value {
+			if channel, ok := channel.(string); ok && channel != "" {
+				channels = append(channels, channel)
+			}
+		}
+	}
+
+	return channels
+}
+
 func EmitEvent(provider string, providerId string, event types.BreakfastEvent) {
 	eventId := security.RandomString(15)
 
@@ -46,26 +78,6 @@ func EmitEvent(provider string, providerId string, event types.BreakfastEvent) {
 	if slices.Contains(SavedEventTypes, event.Type) {
 		event.Id = &eventId
 		go func() {
-			{
-				exists, _ := pb.Dao().FindFirstRecordByFilter(
-					"events",
-					"providerId = {:providerId} && provider = {:provider}",
-					dbx.Params{
-						"providerId": providerId,
-						"provider":   provider,
-					},
-				)
-
-				if exists != nil {
-					pb.Logger().Debug(
-						"EVENTS Received an event that's already been processed. Skipping...",
-						"provider", provider,
-						"providerId", providerId,
-					)
-					return
-				}
-			}
-
 			collection, err := pb.Dao().FindCollectionByNameOrId("events")
 			if err != nil {
 				pb.App.Logger().Error(
@@ -94,16 +106,24 @@ func EmitEvent(provider string, providerId string, event types.BreakfastEvent) {
 		}()
 	}
 
+	for _, hook := range eventHooks {
+		go hook(provider, providerId, event)
+	}
+
 	// Send event to all clients
 	for _, client := range pb.SubscriptionsBroker().Clients() {
 		if client.IsDiscarded() {
 			continue
 		}
-		for sub := range client.Subscriptions() {
+		for sub, options := range client.Subscriptions() {
 			if !strings.HasPrefix(sub, types.BreakfastEventsKey) {
 				continue
 			}
 
+			if !types.MatchesChannels(event.Data, subscriptionChannels(options)) {
+				continue
+			}
+
 			data, err := json.Marshal(event)
 			if err != nil {
 				pb.Logger().Error(
@@ -1,9 +1,14 @@
 package events
 
 import (
+	"breakfast/services/events/badges"
+	"breakfast/services/events/channels"
+	"breakfast/services/events/earnings"
 	"breakfast/services/events/emotes"
 	"breakfast/services/events/listener"
+	"breakfast/services/events/streams"
 	"breakfast/services/events/twitch"
+	"breakfast/services/events/watchtime"
 	"time"
 
 	"github.com/pocketbase/dbx"
@@ -65,12 +70,26 @@ func purgeAllEvents(app *pocketbase.PocketBase) error {
 
 func RegisterService(app *pocketbase.PocketBase) {
 	listener.SetupListener(app)
+	channels.RegisterService(app)
+	streams.RegisterService(app)
 	emotes.RegisterService(app)
+	badges.RegisterService(app)
 	twitch.RegisterService(app)
+	earnings.RegisterService(app)
+	watchtime.RegisterService(app)
 
 	registerSettingsAPIs(app)
 }
 
+// Jobs the event services need to keep working. The emote refresh and event cleanup in
+// RegisterJobs aren't scheduled.
+func RegisterServiceJobs(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	badges.RegisterJobs(app, scheduler)
+	twitch.RegisterJobs(app, scheduler)
+	earnings.RegisterJobs(app, scheduler)
+	watchtime.RegisterJobs(app, scheduler)
+}
+
 func RegisterJobs(app *pocketbase.PocketBase, scheduler *cron.Cron) {
 	emotes.RegisterJobs(app, scheduler)
 
@@ -0,0 +1,26 @@
+package streams
+
+import (
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func RegisterService(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		err := Load()
+		if err != nil {
+			app.Logger().Error("STREAMS Failed to load live stream sessions", "error", err.Error())
+		}
+
+		return nil
+	})
+
+	// Sessions of a deleted channel are cascade deleted
+	app.OnRecordAfterDeleteRequest("channels").Add(func(e *core.RecordDeleteEvent) error {
+		liveLock.Lock()
+		delete(live, e.Record.Id)
+		liveLock.Unlock()
+
+		return nil
+	})
+}
@@ -0,0 +1,201 @@
+package streams
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/channels"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+/*
+Channel - the id of the channel record the stream was on
+Ended - zero while the stream is live
+*/
+type Session struct {
+	Id      string    `json:"id"`
+	Channel string    `json:"channel"`
+	Started time.Time `json:"started"`
+	Ended   time.Time `json:"ended"`
+}
+
+func (s Session) Live() bool {
+	return s.Ended.IsZero()
+}
+
+// Live sessions keyed by channel record id
+var live map[string]Session = make(map[string]Session)
+var liveLock sync.RWMutex
+
+func fromRecord(record *models.Record) Session {
+	return Session{
+		Id:      record.Id,
+		Channel: record.GetString("channel"),
+		Started: record.GetDateTime("started").Time(),
+		Ended:   record.GetDateTime("ended").Time(),
+	}
+}
+
+// Gets the live session of a channel by its channel record id
+func Current(channelId string) (Session, bool) {
+	liveLock.RLock()
+	defer liveLock.RUnlock()
+
+	session, exists := live[channelId]
+	return session, exists
+}
+
+// Gets the live session of a channel by its provider id
+func CurrentForProvider(provider string, providerId string) (Session, bool) {
+	channel, exists := channels.Get(provider, providerId)
+	if !exists || channel.Id == "" {
+		return Session{}, false
+	}
+
+	return Current(channel.Id)
+}
+
+// All live sessions
+func AllLive() []Session {
+	liveLock.RLock()
+	defer liveLock.RUnlock()
+
+	all := make([]Session, 0, len(live))
+	for _, session := range live {
+		all = append(all, session)
+	}
+
+	return all
+}
+
+// Gets the most recent session of a channel, live or not
+func Latest(channelId string) (Session, error) {
+	records, err := services.App.Dao().FindRecordsByFilter(
+		"stream_sessions",
+		"channel = {:channel}",
+		"-started",
+		1,
+		0,
+		dbx.Params{"channel": channelId},
+	)
+	if err != nil {
+		return Session{}, err
+	}
+
+	if len(records) == 0 {
+		return Session{}, errors.New("channel has no stream sessions")
+	}
+
+	return fromRecord(records[0]), nil
+}
+
+func end(channelId string, ended time.Time) error {
+	records, err := services.App.Dao().FindRecordsByFilter(
+		"stream_sessions",
+		"channel = {:channel} && ended = ''",
+		"",
+		0,
+		0,
+		dbx.Params{"channel": channelId},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		record.Set("ended", ended)
+
+		err := services.App.Dao().SaveRecord(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	liveLock.Lock()
+	delete(live, channelId)
+	liveLock.Unlock()
+
+	return nil
+}
+
+// Starts a session for a channel going live. A session left open because the offline
+// event was missed is ended when the next one starts.
+func Start(provider string, providerId string, started time.Time) (Session, error) {
+	channel, exists := channels.Get(provider, providerId)
+	if !exists || channel.Id == "" {
+		return Session{}, errors.New("channel is not watched")
+	}
+
+	if current, exists := Current(channel.Id); exists && current.Started.Equal(started) {
+		return current, nil
+	}
+
+	{
+		err := end(channel.Id, started)
+		if err != nil {
+			return Session{}, err
+		}
+	}
+
+	collection, err := services.App.Dao().FindCollectionByNameOrId("stream_sessions")
+	if err != nil {
+		return Session{}, err
+	}
+
+	record := models.NewRecord(collection)
+	record.RefreshId()
+	record.Set("channel", channel.Id)
+	record.Set("started", started)
+
+	{
+		err := services.App.Dao().SaveRecord(record)
+		if err != nil {
+			return Session{}, err
+		}
+	}
+
+	session := fromRecord(record)
+
+	liveLock.Lock()
+	live[channel.Id] = session
+	liveLock.Unlock()
+
+	return session, nil
+}
+
+// Ends the live session of a channel going offline
+func End(provider string, providerId string, ended time.Time) error {
+	channel, exists := channels.Get(provider, providerId)
+	if !exists || channel.Id == "" {
+		return errors.New("channel is not watched")
+	}
+
+	return end(channel.Id, ended)
+}
+
+// Loads the sessions that are still live, keeping the latest for each channel
+func Load() error {
+	records, err := services.App.Dao().FindRecordsByFilter(
+		"stream_sessions",
+		"ended = ''",
+		"started",
+		0,
+		0,
+	)
+	if err != nil {
+		return err
+	}
+
+	liveLock.Lock()
+	defer liveLock.Unlock()
+
+	for _, record := range records {
+		session := fromRecord(record)
+		live[session.Channel] = session
+	}
+
+	return nil
+}
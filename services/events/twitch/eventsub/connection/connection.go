@@ -118,7 +118,17 @@ func Connect(url string) error {
 					continue
 				}
 
-				keepalive_timeout := message.Payload["session"].(map[string]any)["keepalive_timeout_seconds"].(float64)
+				var payload EventSubSessionPayload
+				{
+					err := json.Unmarshal(message.Payload, &payload)
+					if err != nil || payload.Session.Id == "" {
+						welcome <- "error"
+						closed = true
+						continue
+					}
+				}
+
+				keepalive_timeout := payload.Session.KeepaliveTimeoutSeconds
 				keepalive_duration = time.Second * time.Duration(keepalive_timeout+5) // We add a bit of extra buffer cause latency
 				keepalive = time.NewTimer(keepalive_duration)
 
@@ -141,7 +151,7 @@ func Connect(url string) error {
 					closed = true
 				}()
 
-				welcome <- message.Payload["session"].(map[string]any)["id"].(string)
+				welcome <- payload.Session.Id
 				welcome_sent = true
 
 				services.App.Logger().Debug(
@@ -152,8 +162,19 @@ func Connect(url string) error {
 			case "session_reconnect":
 				closed = true
 
-				reconnect_url := message.Payload["session"].(map[string]any)["reconnect_url"].(string)
-				err := Connect(reconnect_url)
+				var payload EventSubSessionPayload
+				{
+					err := json.Unmarshal(message.Payload, &payload)
+					if err != nil {
+						services.App.Logger().Error(
+							"EVENTS Twitch eventsub was requested to reconnect with a bad payload",
+							"error", err.Error(),
+						)
+						break
+					}
+				}
+
+				err := Connect(payload.Session.ReconnectUrl)
 				if err != nil {
 					services.App.Logger().Error(
 						"EVENTS Twitch eventsub was requested to reconnect but failed",
@@ -172,27 +193,31 @@ func Connect(url string) error {
 				// TODO: Revoke subscription
 			case "notification":
 				keepalive.Reset(keepalive_duration)
-				if eventHook == nil {
-					break
-				}
 
-				eventSub, ok := message.Payload["subscription"].(map[string]any)
-				if !ok {
-					services.App.Logger().Error(
-						"EVENTS Twitch eventsub got a bad subscription from twitch",
-					)
-					break
+				var payload EventSubNotificationPayload
+				{
+					err := json.Unmarshal(message.Payload, &payload)
+					if err != nil {
+						services.App.Logger().Error(
+							"EVENTS Twitch eventsub got a bad notification from twitch",
+							"error", err.Error(),
+						)
+						break
+					}
 				}
-				subscription, err := SubscriptionFromPayload(eventSub)
-				if err != nil {
-					services.App.Logger().Error(
-						"EVENTS Twitch eventsub failed to parse subscription",
-						"error", err.Error(),
-					)
-					break
+
+				{
+					err := payload.Subscription.validate()
+					if err != nil {
+						services.App.Logger().Error(
+							"EVENTS Twitch eventsub failed to parse subscription",
+							"error", err.Error(),
+						)
+						break
+					}
 				}
 
-				eventHook(&message, subscription)
+				dispatchNotification(&message, &payload.Subscription, payload.Event)
 			}
 		}
 
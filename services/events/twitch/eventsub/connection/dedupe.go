@@ -0,0 +1,124 @@
+package connection
+
+import (
+	"breakfast/services"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/cron"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Twitch recommends rejecting any message older than 10 minutes, so message ids only
+// need to be remembered for that long to catch every replay
+const MessageMaxAge = 10 * time.Minute
+
+const dedupe_job_name = "twitchEventSubMessagePrune"
+const dedupe_job_cron = "*/10 * * * *"
+
+// Checks a message is recent and hasn't been seen before, marking it as seen.
+// Inserting the id is atomic so concurrent deliveries of the same message will
+// only ever let one through.
+func verifyMessage(metadata EventSubMessageMetadata) error {
+	timestamp, err := time.Parse(time.RFC3339Nano, metadata.MessageTimestamp)
+	if err != nil {
+		return errors.Join(errors.New("message timestamp is not valid"), err)
+	}
+
+	if time.Since(timestamp) > MessageMaxAge {
+		return ErrMessageTooOld
+	}
+
+	if metadata.MessageId == "" {
+		return errors.New("message id is empty")
+	}
+
+	received, err := types.ParseDateTime(time.Now())
+	if err != nil {
+		return err
+	}
+
+	result, err := services.App.Dao().DB().
+		NewQuery("INSERT OR IGNORE INTO twitch_eventsub_messages (id, received) VALUES ({:id}, {:received})").
+		Bind(dbx.Params{
+			"id":       metadata.MessageId,
+			"received": received.String(),
+		}).
+		Execute()
+	if err != nil {
+		return err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if inserted == 0 {
+		return ErrDuplicateMessage
+	}
+
+	return nil
+}
+
+// Verifies the message and hands it to the event hook. All transports should deliver
+// notifications through here so replays are handled the same way everywhere.
+func dispatchNotification(message *EventSubMessage, subscription *Subscription, event json.RawMessage) {
+	if eventHook == nil {
+		return
+	}
+
+	err := verifyMessage(message.Metadata)
+	if errors.Is(err, ErrDuplicateMessage) {
+		services.App.Logger().Debug(
+			"EVENTS Twitch eventsub received a message that's already been processed. Skipping...",
+			"messageId", message.Metadata.MessageId,
+		)
+		return
+	}
+
+	if err != nil {
+		services.App.Logger().Warn(
+			"EVENTS Twitch eventsub rejected a message",
+			"messageId", message.Metadata.MessageId,
+			"timestamp", message.Metadata.MessageTimestamp,
+			"error", err.Error(),
+		)
+		return
+	}
+
+	eventHook(message, subscription, event)
+}
+
+func PruneSeenMessages(app *pocketbase.PocketBase) error {
+	before, err := types.ParseDateTime(time.Now().Add(MessageMaxAge * -1))
+	if err != nil {
+		return err
+	}
+
+	_, err = app.Dao().DB().
+		Delete("twitch_eventsub_messages", dbx.NewExp(
+			"received < {:before}",
+			dbx.Params{"before": before.String()},
+		)).
+		Execute()
+
+	return err
+}
+
+func ScheduleSeenMessagePrune(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	scheduler.MustAdd(dedupe_job_name, dedupe_job_cron, func() {
+		err := PruneSeenMessages(app)
+		if err != nil {
+			app.Logger().Error(
+				"JOBS Failed to prune seen twitch eventsub messages",
+				"error", err.Error(),
+				"job", dedupe_job_name,
+				"cron", dedupe_job_cron,
+			)
+		}
+	})
+}
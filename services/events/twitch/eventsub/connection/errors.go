@@ -5,3 +5,5 @@ import "errors"
 var ErrAlreadyConnected = errors.New("socket already connected")
 var ErrNotConnected = errors.New("socket not connected")
 var ErrAlreadSubscribed = errors.New("a subscription with that config already exists")
+var ErrDuplicateMessage = errors.New("message has already been received")
+var ErrMessageTooOld = errors.New("message is older than the allowed age")
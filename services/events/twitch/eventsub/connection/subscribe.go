@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"maps"
 	"net/http"
 
 	"github.com/pocketbase/dbx"
@@ -14,6 +15,10 @@ import (
 
 var activeSubscriptions map[string]*Subscription = make(map[string]*Subscription)
 
+// Costs as last reported by twitch when subscribing
+var totalCost int = 0
+var maxTotalCost int = 0
+
 func requestSubscription(subType string, subVersion string, subCondition map[string]string, accessToken string) (*SubscriptionResponse, error) {
 	if ws == nil || sessionId == "" {
 		err := Connect(EventSubWsUrl)
@@ -88,13 +93,20 @@ func getAuthorizerToken(userId string) (string, error) {
 }
 
 func Subscribe(id string, sub subscriptions.SubscriptionConfig, authorizerId string) (*Subscription, error) {
+	{
+		err := subscriptions.ValidateConfig(sub)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	token, err := getAuthorizerToken(authorizerId)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, active := range activeSubscriptions {
-		if active.Type == sub.Type && active.Condition["broadcaster_user_id"] == sub.Condition["broadcaster_user_id"] {
+		if active.Type == sub.Type && maps.Equal(active.Condition, sub.Condition) {
 			return nil, ErrAlreadSubscribed
 		}
 	}
@@ -115,12 +127,16 @@ func Subscribe(id string, sub subscriptions.SubscriptionConfig, authorizerId str
 
 	subscription := Subscription{
 		Id:        resp.Data[0].Id,
+		Status:    resp.Data[0].Status,
 		Type:      resp.Data[0].Type,
 		Version:   resp.Data[0].Version,
 		Condition: resp.Data[0].Condition,
+		Cost:      resp.Data[0].Cost,
 	}
 
 	activeSubscriptions[id] = &subscription
+	totalCost = resp.TotalCost
+	maxTotalCost = resp.MaxTotalCost
 
 	return &subscription, nil
 }
@@ -161,6 +177,7 @@ func Unsubscribe(id string) error {
 	}
 
 	delete(activeSubscriptions, id)
+	totalCost -= subscription.Cost
 
 	if len(activeSubscriptions) == 0 {
 		Disconnect()
@@ -168,3 +185,13 @@ func Unsubscribe(id string) error {
 
 	return nil
 }
+
+func GetActive(id string) (*Subscription, bool) {
+	subscription, exists := activeSubscriptions[id]
+	return subscription, exists
+}
+
+// Total cost of active subscriptions and the max allowed, as last reported by twitch
+func Costs() (int, int) {
+	return totalCost, maxTotalCost
+}
@@ -1,52 +1,35 @@
 package connection
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+)
 
-type EventHook func(message *EventSubMessage, subscription *Subscription)
+type EventHook func(message *EventSubMessage, subscription *Subscription, event json.RawMessage)
 
 type Subscription struct {
-	Id        string
-	Type      string
-	Version   string
-	Condition map[string]string
+	Id        string            `json:"id"`
+	Status    string            `json:"status"`
+	Type      string            `json:"type"`
+	Version   string            `json:"version"`
+	Condition map[string]string `json:"condition"`
+	Cost      int               `json:"cost"`
 }
 
-func SubscriptionFromPayload(payload map[string]any) (*Subscription, error) {
-	id, ok := payload["id"].(string)
-	if !ok {
-		return nil, errors.New("id field is not of the correct type")
-	}
-
-	subType, ok := payload["type"].(string)
-	if !ok {
-		return nil, errors.New("type field is not of the correct type")
+func (s *Subscription) validate() error {
+	if s.Id == "" {
+		return errors.New("id field is missing")
 	}
 
-	version, ok := payload["version"].(string)
-	if !ok {
-		return nil, errors.New("version field is not of the correct type")
+	if s.Type == "" {
+		return errors.New("type field is missing")
 	}
 
-	condition, ok := payload["condition"].(map[string]any)
-	if !ok {
-		return nil, errors.New("condition field is not of the correct type")
+	if s.Version == "" {
+		return errors.New("version field is missing")
 	}
 
-	validCondition := map[string]string{}
-	for key, maybe := range condition {
-		value, ok := maybe.(string)
-		if !ok {
-			return nil, errors.New(key + " condition subfield is not of the correct type")
-		}
-		validCondition[key] = value
-	}
-
-	return &Subscription{
-		Id:        id,
-		Type:      subType,
-		Version:   version,
-		Condition: validCondition,
-	}, nil
+	return nil
 }
 
 type Transport struct {
@@ -96,5 +79,25 @@ type EventSubMessageMetadata struct {
 
 type EventSubMessage struct {
 	Metadata EventSubMessageMetadata `json:"metadata"`
-	Payload  map[string]any          `json:"payload"`
+	Payload  json.RawMessage         `json:"payload"`
+}
+
+type EventSubSession struct {
+	Id                      string `json:"id"`
+	Status                  string `json:"status"`
+	KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+	ReconnectUrl            string `json:"reconnect_url"`
+	ConnectedAt             string `json:"connected_at"`
+}
+
+// Payload of session_welcome and session_reconnect messages
+type EventSubSessionPayload struct {
+	Session EventSubSession `json:"session"`
+}
+
+// Payload of notification and revocation messages, the event is left raw to be
+// decoded by the schema registered for the subscription type and version
+type EventSubNotificationPayload struct {
+	Subscription Subscription    `json:"subscription"`
+	Event        json.RawMessage `json:"event"`
 }
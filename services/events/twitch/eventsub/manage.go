@@ -2,16 +2,25 @@ package eventsub
 
 import (
 	"breakfast/services"
+	"breakfast/services/events/channels"
 	"breakfast/services/events/twitch/eventsub/connection"
 	"breakfast/services/events/twitch/eventsub/subscriptions"
 
 	"github.com/pocketbase/pocketbase/models"
 )
 
-func CreateSubscription(userId string, config subscriptions.SubscriptionConfig) (string, error) {
+// Saves and subscribes a subscription. If twitch rejects the subscription the record is removed again.
+func CreateSubscription(userId string, config subscriptions.SubscriptionConfig) (*models.Record, *connection.Subscription, error) {
+	{
+		err := subscriptions.ValidateConfig(config)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	collection, err := services.App.Dao().FindCollectionByNameOrId("twitch_event_subscriptions")
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
 
 	record := models.NewRecord(collection)
@@ -22,18 +31,32 @@ func CreateSubscription(userId string, config subscriptions.SubscriptionConfig)
 	{
 		err := services.App.Dao().SaveRecord(record)
 		if err != nil {
-			return "", err
+			return nil, nil, err
 		}
 	}
 
-	{
-		_, err := connection.Subscribe(record.Id, config, userId)
-		if err != nil {
-			return "", err
+	subscription, err := connection.Subscribe(record.Id, config, userId)
+	if err != nil {
+		{
+			err := services.App.Dao().DeleteRecord(record)
+			if err != nil {
+				services.App.Logger().Error(
+					"EVENTS Failed to remove twitch eventsub subscription that failed to subscribe",
+					"subscription", record.Id,
+					"error", err.Error(),
+				)
+			}
 		}
+
+		return nil, nil, err
+	}
+
+	// Watch the channel so its metadata and emotes are loaded
+	if broadcasterId := config.BroadcasterId(); broadcasterId != "" {
+		channels.Ensure("twitch", broadcasterId)
 	}
 
-	return record.Id, nil
+	return record, subscription, nil
 }
 
 func DeleteSubscription(subscriptionId string) error {
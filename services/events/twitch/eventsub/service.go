@@ -17,102 +17,14 @@ import (
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/cron"
 )
 
 func RegisterService(app *pocketbase.PocketBase) {
-	connection.SetEventHook(func(message *connection.EventSubMessage, subscription *connection.Subscription) {
-		var eventType string
-		var eventData any
-
-		switch subscription.Type {
-		case subscriptions.TypeStreamOnline:
-			data, err := subscriptions.ProcessStreamOnlinePayload(message.Payload)
-			if err != nil {
-				app.Logger().Error(
-					"EVENTS Failed to conform twitch stream online to type",
-					"error", err.Error(),
-				)
-				return
-			}
-			eventType = types.EventTypeStreamOnline
-			eventData = data
-		case subscriptions.TypeStreamOffline:
-			data, err := subscriptions.ProcessStreamOfflinePayload(message.Payload)
-			if err != nil {
-				app.Logger().Error(
-					"EVENTS Failed to conform twitch stream offline to type",
-					"error", err.Error(),
-				)
-				return
-			}
-			eventType = types.EventTypeStreamOffline
-			eventData = data
-		case subscriptions.TypeChannelChatMessage:
-			data, err := subscriptions.ProcessChannelChatMessageEventPayload(message.Payload)
-			if err != nil {
-				app.Logger().Error(
-					"EVENTS Failed to conform twitch chat message to type",
-					"error", err.Error(),
-				)
-				return
-			}
-			eventType = types.EventTypeChatMessage
-			eventData = data
-		case subscriptions.TypeChannelSubscribe:
-			data, err := subscriptions.ProcessChannelSubscribePayload(message.Payload)
-			if err != nil {
-				app.Logger().Error(
-					"EVENTS Failed to conform twitch channel subscribe message to type",
-					"error", err.Error(),
-				)
-				return
-			}
-			eventType = types.EventTypeSubscription
-			eventData = data
-		case subscriptions.TypeChannelChatMessageDelete:
-			data, err := subscriptions.ProcessChannelChatMessageDeletePayload(message.Payload)
-			if err != nil {
-				app.Logger().Error(
-					"EVENTS Failed to conform twitch channel message delete to type",
-					"error", err.Error(),
-				)
-			}
-			eventType = types.EventTypeChatMessageDelete
-			eventData = data
-		case subscriptions.TypeChannelPointsRedeemAdd:
-			data, err := subscriptions.ProcessChannelPointsRedeemAddPayload(message.Payload)
-			if err != nil {
-				app.Logger().Error(
-					"EVENTS Failed to conform twitch channel points redeem add to type",
-					"error", err.Error(),
-				)
-			}
-			eventType = types.EventTypeCurrencySpent
-			eventData = data
-			if data.Viewer != nil {
-				currentCount, exists := data.Viewer.Wallet["channel points"]
-				if !exists {
-					currentCount = 0
-				}
-
-				_, err := app.Dao().DB().
-					NewQuery(
-						"UPDATE viewers SET wallet = json_patch(wallet, json_object('channel points', {:amount})) WHERE id = {:id}",
-					).
-					Bind(dbx.Params{
-						"amount": currentCount - data.Redeemed.Cost,
-						"id":     data.Viewer.Id,
-					}).
-					Execute()
-
-				if err != nil {
-					app.Logger().Error(
-						"EVENTS Twitch channel points were redeemed but unable to update viewer count",
-						"error", err.Error(),
-					)
-				}
-			}
-		default:
+	connection.SetEventHook(func(message *connection.EventSubMessage, subscription *connection.Subscription, event json.RawMessage) {
+		definition, exists := subscriptions.Get(subscription.Type)
+		if !exists {
 			app.Logger().Error(
 				"EVENTS Twitch eventsub processed an event which isn't handled",
 				"type", subscription.Type,
@@ -120,20 +32,34 @@ func RegisterService(app *pocketbase.PocketBase) {
 			return
 		}
 
-		if eventData == nil || eventType == "" {
+		eventData, err := definition.Process(subscription.Version, event)
+		if err != nil {
 			app.Logger().Error(
-				"EVENTS Twitch eventsub processed an event but didn't assign data",
-				"type", eventType,
+				"EVENTS Failed to conform twitch eventsub event to type",
+				"type", subscription.Type,
+				"version", subscription.Version,
+				"error", err.Error(),
 			)
 			return
 		}
 
+		for _, hook := range definition.Hooks {
+			err := hook(eventData)
+			if err != nil {
+				app.Logger().Error(
+					"EVENTS Twitch eventsub hook failed for event",
+					"type", subscription.Type,
+					"error", err.Error(),
+				)
+			}
+		}
+
 		listener.EmitEvent(
 			"twitch-eventsub",
 			message.Metadata.MessageId,
 			types.BreakfastEvent{
 				Id:       nil,
-				Type:     eventType,
+				Type:     definition.EventType,
 				Platform: "twitch",
 				Data:     eventData,
 			},
@@ -224,12 +150,8 @@ func RegisterService(app *pocketbase.PocketBase) {
 
 			subs := subscriptions.CreateDefaultSubscriptions(external.ProviderId)
 			for _, sub := range subs {
-				recordId, err := CreateSubscription(user.Id, sub)
+				_, _, err := CreateSubscription(user.Id, sub)
 				if errors.Is(err, connection.ErrAlreadSubscribed) {
-					record, _ := app.Dao().FindRecordById("twitch_event_subscriptions", recordId)
-					if record != nil {
-						app.Dao().DeleteRecord(record)
-					}
 					continue
 				}
 
@@ -246,6 +168,49 @@ func RegisterService(app *pocketbase.PocketBase) {
 			return c.JSON(200, map[string]string{"message": "OK"})
 		})
 
+		e.Router.GET("/api/breakfast/events/twitch/eventsub/types", func(c echo.Context) error {
+			// Validate user is authenticated
+			info := apis.RequestInfo(c)
+			user := info.AuthRecord
+
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			if user.Collection().Id != "users" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			type subscriptionType struct {
+				Type      string   `json:"type"`
+				Version   string   `json:"version"`
+				EventType string   `json:"eventType"`
+				Scopes    []string `json:"scopes"`
+				Bundle    string   `json:"bundle"`
+				Default   bool     `json:"default"`
+
+				Condition []subscriptions.ConditionField `json:"condition"`
+			}
+
+			response := []subscriptionType{}
+			for _, definition := range subscriptions.All() {
+				response = append(response, subscriptionType{
+					Type:      definition.Type,
+					Version:   definition.Version,
+					EventType: definition.EventType,
+					Scopes:    definition.Scopes,
+					Bundle:    definition.Bundle,
+					Default:   definition.Default,
+					Condition: definition.Condition,
+				})
+			}
+
+			return c.JSON(200, map[string]any{
+				"types":  response,
+				"scopes": subscriptions.RequiredScopes(),
+			})
+		})
+
 		e.Router.POST("/api/breakfast/events/twitch/eventsub/subscribe", func(c echo.Context) error {
 			// Validate user is authenticated
 			info := apis.RequestInfo(c)
@@ -272,7 +237,9 @@ func RegisterService(app *pocketbase.PocketBase) {
 			}
 
 			var request struct {
-				Type string         `json:"type"`
+				Type      string            `json:"type"`
+				Condition map[string]string `json:"condition"`
+				// Deprecated: only supports broadcasterId and broadcasterLogin, use condition instead
 				Data map[string]any `json:"data"`
 			}
 			{
@@ -282,72 +249,172 @@ func RegisterService(app *pocketbase.PocketBase) {
 				}
 			}
 
-			// Get broadcaster from request
-			broadcasterId, idOk := request.Data["broadcasterId"].(string)
-			broadcasterLogin, loginOk := request.Data["broadcasterLogin"].(string)
+			condition := request.Condition
+			if condition == nil {
+				condition = map[string]string{}
+				if broadcasterId, ok := request.Data["broadcasterId"].(string); ok {
+					condition["broadcaster_user_id"] = broadcasterId
+				} else if broadcasterLogin, ok := request.Data["broadcasterLogin"].(string); ok {
+					condition["broadcaster_user_login"] = broadcasterLogin
+				}
+			}
 
-			if !idOk && !loginOk {
-				return c.JSON(400, map[string]string{"message": "No valid broadcaster id or login to use"})
+			definitions := subscriptions.Bundle(request.Type)
+			if len(definitions) == 0 {
+				definition, exists := subscriptions.Get(request.Type)
+				if !exists {
+					return c.JSON(400, map[string]string{"message": "Cant make a subscription of that type"})
+				}
+
+				definitions = append(definitions, definition)
 			}
 
-			var id string
-			if idOk {
-				id = broadcasterId
-			} else {
-				user, err := bapis.GetTwitchUserByLogin(broadcasterLogin)
+			resolveLogin := func(login string) (string, error) {
+				user, err := bapis.GetTwitchUserByLogin(login)
 				if err != nil {
-					return c.JSON(500, map[string]string{"message": "Failed to find user with provided login", "error": err.Error()})
+					return "", err
 				}
 
-				id = user.Id
+				return user.Id, nil
 			}
 
-			if id == "" {
-				return c.JSON(400, map[string]string{"message": "Broadcaster cannot be empty"})
+			// Validate every config before subscribing so a bad condition doesn't leave half a bundle subscribed
+			configs := []subscriptions.SubscriptionConfig{}
+			for _, definition := range definitions {
+				config, err := definition.BuildConfig(condition, authorizerTwitchRecord.ProviderId, resolveLogin)
+				if err != nil {
+					return c.JSON(400, map[string]string{"message": "Invalid condition", "error": err.Error()})
+				}
+
+				configs = append(configs, config)
 			}
 
-			switch request.Type {
-			case "chat":
-				errs := []error{}
-				{
-					config := subscriptions.CreateChannelChatMessageSubscription(id, authorizerTwitchRecord.ProviderId)
-					_, err := CreateSubscription(user.Id, config)
-					errs = append(errs, err)
-				}
+			type createdSubscription struct {
+				Record *models.Record `json:"record"`
+				Status string         `json:"status"`
+				Cost   int            `json:"cost"`
+			}
 
-				{
-					config := subscriptions.CreateChannelChatMessageDeleteSubscription(id, authorizerTwitchRecord.ProviderId)
-					_, err := CreateSubscription(user.Id, config)
+			created := []createdSubscription{}
+			errs := []error{}
+			for _, config := range configs {
+				record, subscription, err := CreateSubscription(user.Id, config)
+				if err != nil {
 					errs = append(errs, err)
+					continue
 				}
 
+				created = append(created, createdSubscription{
+					Record: record,
+					Status: subscription.Status,
+					Cost:   subscription.Cost,
+				})
+			}
+
+			totalCost, maxTotalCost := connection.Costs()
+			response := map[string]any{
+				"message":       "OK",
+				"subscriptions": created,
+				"totalCost":     totalCost,
+				"maxTotalCost":  maxTotalCost,
+			}
+
+			{
 				err := errors.Join(errs...)
 				if err != nil {
-					return c.JSON(500, map[string]string{"message": "Failed to create subscriptions for chat", "error": err.Error()})
+					response["message"] = "Failed to create subscriptions for " + request.Type
+					response["error"] = err.Error()
+					return c.JSON(500, response)
 				}
-			case "live":
-				errs := []error{}
+			}
+
+			return c.JSON(200, response)
+		})
+
+		e.Router.GET("/api/breakfast/events/twitch/eventsub/subscriptions", func(c echo.Context) error {
+			// Validate user is authenticated
+			info := apis.RequestInfo(c)
+			user := info.AuthRecord
+
+			if user == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			if user.Collection().Id != "users" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			records, err := app.Dao().FindRecordsByExpr("twitch_event_subscriptions")
+			if err != nil {
+				return c.JSON(500, map[string]string{"message": "Failed to query subscriptions", "error": err.Error()})
+			}
+
+			type listedSubscription struct {
+				Id         string            `json:"id"`
+				Authorizer string            `json:"authorizer"`
+				Type       string            `json:"type"`
+				Version    string            `json:"version"`
+				Condition  map[string]string `json:"condition"`
+				Active     bool              `json:"active"`
+				Status     string            `json:"status"`
+				Cost       int               `json:"cost"`
+			}
+
+			type broadcasterSubscriptions struct {
+				BroadcasterId string               `json:"broadcasterId"`
+				Subscriptions []listedSubscription `json:"subscriptions"`
+			}
+
+			broadcasters := []*broadcasterSubscriptions{}
+			byBroadcaster := map[string]*broadcasterSubscriptions{}
+			for _, record := range records {
+				var config subscriptions.SubscriptionConfig
 				{
-					config := subscriptions.CreateStreamOnlineSubscription(id)
-					_, err := CreateSubscription(user.Id, config)
-					errs = append(errs, err)
+					err := record.UnmarshalJSONField("config", &config)
+					if err != nil {
+						app.Logger().Warn(
+							"EVENTS Twitch eventsub subscription has an invalid config",
+							"subscription", record.Id,
+							"error", err.Error(),
+						)
+						continue
+					}
 				}
 
-				{
-					config := subscriptions.CreateStreamOfflineSubscription(id)
-					_, err := CreateSubscription(user.Id, config)
-					errs = append(errs, err)
+				listed := listedSubscription{
+					Id:         record.Id,
+					Authorizer: record.GetString("authorizer"),
+					Type:       config.Type,
+					Version:    config.Version,
+					Condition:  config.Condition,
 				}
 
-				err := errors.Join(errs...)
-				if err != nil {
-					return c.JSON(500, map[string]string{"message": "Failed to create subscriptions for live", "error": err.Error()})
+				if active, exists := connection.GetActive(record.Id); exists {
+					listed.Active = true
+					listed.Status = active.Status
+					listed.Cost = active.Cost
+				}
+
+				broadcasterId := config.BroadcasterId()
+				group, exists := byBroadcaster[broadcasterId]
+				if !exists {
+					group = &broadcasterSubscriptions{
+						BroadcasterId: broadcasterId,
+						Subscriptions: []listedSubscription{},
+					}
+					byBroadcaster[broadcasterId] = group
+					broadcasters = append(broadcasters, group)
 				}
-			default:
-				return c.JSON(400, map[string]string{"message": "Cant make a subscription of that type"})
+
+				group.Subscriptions = append(group.Subscriptions, listed)
 			}
 
-			return c.JSON(200, map[string]string{"message": "OK"})
+			totalCost, maxTotalCost := connection.Costs()
+			return c.JSON(200, map[string]any{
+				"broadcasters": broadcasters,
+				"totalCost":    totalCost,
+				"maxTotalCost": maxTotalCost,
+			})
 		})
 
 		e.Router.POST("/api/breakfast/events/twitch/eventsub/unsubscribe/:id", func(c echo.Context) error {
@@ -439,3 +506,10 @@ func RegisterService(app *pocketbase.PocketBase) {
 		return nil
 	})
 }
+
+func RegisterJobs(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		connection.ScheduleSeenMessagePrune(app, scheduler)
+		return nil
+	})
+}
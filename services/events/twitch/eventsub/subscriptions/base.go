@@ -6,13 +6,26 @@ type SubscriptionConfig struct {
 	Condition map[string]string `json:"condition"`
 }
 
+// The broadcaster a subscription is for, raids use whichever side of the raid was subscribed
+func (c SubscriptionConfig) BroadcasterId() string {
+	for _, key := range []string{"broadcaster_user_id", "to_broadcaster_user_id", "from_broadcaster_user_id"} {
+		if id, exists := c.Condition[key]; exists && id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
+
 func CreateDefaultSubscriptions(twitchUserId string) []SubscriptionConfig {
-	return []SubscriptionConfig{
-		CreateChannelChatMessageSubscription(twitchUserId, twitchUserId),
-		CreateChannelChatMessageDeleteSubscription(twitchUserId, twitchUserId),
-		CreateChannelSubscribeSubscription(twitchUserId),
-		CreateStreamOfflineSubscription(twitchUserId),
-		CreateStreamOnlineSubscription(twitchUserId),
-		CreateChannelPointsRedeemAddSubscription(twitchUserId),
+	configs := []SubscriptionConfig{}
+	for _, definition := range All() {
+		if !definition.Default {
+			continue
+		}
+
+		configs = append(configs, definition.Create(twitchUserId, twitchUserId))
 	}
+
+	return configs
 }
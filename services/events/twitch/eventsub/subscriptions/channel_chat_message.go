@@ -1,10 +1,11 @@
 package subscriptions
 
 import (
+	"breakfast/services/events/badges"
+	"breakfast/services/events/channels"
 	"breakfast/services/events/emotes"
 	"breakfast/services/events/types"
 	"breakfast/services/viewers"
-	"errors"
 )
 
 const TypeChannelChatMessage = "channel.chat.message"
@@ -20,178 +21,203 @@ func CreateChannelChatMessageSubscription(twitch_broadcaster_id string, twitch_u
 	}
 }
 
-func ProcessChannelChatMessageEventPayload(payload map[string]any) (*types.ChatMessage, error) {
-	event, valid := payload["event"].(map[string]any)
-	if !valid {
-		return nil, errors.New("event field was not of the correct type")
-	}
-	message, valid := event["message"].(map[string]any)
-	if !valid {
-		return nil, errors.New("message field was not of the correct type")
-	}
-	message_text, valid := message["text"].(string)
-	if !valid {
-		return nil, errors.New("text field was not of the correct type")
-	}
-	message_type, valid := event["message_type"].(string)
-	if !valid {
-		return nil, errors.New("message_type was not of the correct type")
-	}
-	broadcaster_user_id, valid := event["broadcaster_user_id"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_id was not of the correct type")
-	}
-	broadcaster_user_login, valid := event["broadcaster_user_login"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_login was not of the correct type")
-	}
-	broadcaster_user_name, valid := event["broadcaster_user_name"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_name was not of the correct type")
-	}
-	chatter_user_id, valid := event["chatter_user_id"].(string)
-	if !valid {
-		return nil, errors.New("chatter_user_id was not of the correct type")
-	}
-	chatter_user_login, valid := event["chatter_user_login"].(string)
-	if !valid {
-		return nil, errors.New("chatter_user_login was not of the correct type")
-	}
-	chatter_user_name, valid := event["chatter_user_name"].(string)
-	if !valid {
-		return nil, errors.New("chatter_user_name was not of the correct type")
-	}
-	message_id, valid := event["message_id"].(string)
-	if !valid {
-		return nil, errors.New("message_id was not of the correct type")
-	}
-	color, valid := event["color"].(string)
-	if !valid {
-		return nil, errors.New("color was not of the correct type")
-	}
-	message_fragments, valid := message["fragments"].([]any)
-	if !valid {
-		return nil, errors.New("fragments field was not of the correct type")
-	}
+type ChannelChatMessageBadgeV1 struct {
+	SetId string `json:"set_id"`
+	Id    string `json:"id"`
+	Info  string `json:"info"`
+}
 
-	var chat_fragments []types.ChatMessageFragment
-	for _, item := range message_fragments {
-		fragment, valid := item.(map[string]any)
-		if !valid {
-			return nil, errors.New("fragment was not of the correct type")
-		}
-		fragment_type, valid := fragment["type"].(string)
-		if !valid {
-			return nil, errors.New("fragment type was not of the correct type")
-		}
-		text, valid := fragment["text"].(string)
-		if !valid {
-			return nil, errors.New("fragment text was not of the correct type")
-		}
+type ChannelChatMessageFragmentV1 struct {
+	Type      string `json:"type"`
+	Text      string `json:"text"`
+	Cheermote *struct {
+		Prefix string `json:"prefix"`
+		Bits   int    `json:"bits"`
+		Tier   int    `json:"tier"`
+	} `json:"cheermote"`
+	Emote *struct {
+		Id         string   `json:"id"`
+		EmoteSetId string   `json:"emote_set_id"`
+		OwnerId    string   `json:"owner_id"`
+		Format     []string `json:"format"`
+	} `json:"emote"`
+	Mention *struct {
+		UserId    string `json:"user_id"`
+		UserName  string `json:"user_name"`
+		UserLogin string `json:"user_login"`
+	} `json:"mention"`
+}
 
-		images := []types.ChatMessageImage{}
-		if fragment_type == "emote" {
-			emote, valid := fragment["emote"].(map[string]any)
-			if !valid {
-				return nil, errors.New("emote was not of the correct type")
-			}
+type ChannelChatMessageEventV1 struct {
+	BroadcasterUserId    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	ChatterUserId        string `json:"chatter_user_id"`
+	ChatterUserLogin     string `json:"chatter_user_login"`
+	ChatterUserName      string `json:"chatter_user_name"`
+	MessageId            string `json:"message_id"`
+	Message              struct {
+		Text      string                         `json:"text"`
+		Fragments []ChannelChatMessageFragmentV1 `json:"fragments"`
+	} `json:"message"`
+	MessageType string                      `json:"message_type"`
+	Color       string                      `json:"color"`
+	Badges      []ChannelChatMessageBadgeV1 `json:"badges"`
+	Cheer       *struct {
+		Bits int `json:"bits"`
+	} `json:"cheer"`
+	Reply *struct {
+		ParentMessageId   string `json:"parent_message_id"`
+		ParentMessageBody string `json:"parent_message_body"`
+		ParentUserId      string `json:"parent_user_id"`
+		ParentUserName    string `json:"parent_user_name"`
+		ParentUserLogin   string `json:"parent_user_login"`
+		ThreadMessageId   string `json:"thread_message_id"`
+		ThreadUserId      string `json:"thread_user_id"`
+		ThreadUserName    string `json:"thread_user_name"`
+		ThreadUserLogin   string `json:"thread_user_login"`
+	} `json:"reply"`
+	ChannelPointsCustomRewardId *string `json:"channel_points_custom_reward_id"`
+	// Set when the message was sent in another channel of a shared chat session
+	SourceBroadcasterUserId    *string                     `json:"source_broadcaster_user_id"`
+	SourceBroadcasterUserLogin *string                     `json:"source_broadcaster_user_login"`
+	SourceBroadcasterUserName  *string                     `json:"source_broadcaster_user_name"`
+	SourceMessageId            *string                     `json:"source_message_id"`
+	SourceBadges               []ChannelChatMessageBadgeV1 `json:"source_badges"`
+}
 
-			emoteId, valid := emote["id"].(string)
-			if !valid {
-				return nil, errors.New("emote id was not of the correct type")
-			}
+func init() {
+	registerSchema(TypeChannelChatMessage, "1", ProcessChannelChatMessageEvent)
+
+	Register(Definition{
+		Type:      TypeChannelChatMessage,
+		Version:   "1",
+		EventType: types.EventTypeChatMessage,
+		Scopes:    []string{"user:read:chat"},
+		Bundle:    "chat",
+		Default:   true,
+		Condition: []ConditionField{broadcasterCondition, authorizerUserCondition},
+		Create:    CreateChannelChatMessageSubscription,
+	})
+}
 
-			formats, valid := emote["format"].([]any)
-			if !valid {
-				return nil, errors.New("emote format not of the correct type")
-			}
+func ProcessChannelChatMessageEvent(event *ChannelChatMessageEventV1) (*types.ChatMessage, error) {
+	chat_fragments := make([]types.ChatMessageFragment, 0, len(event.Message.Fragments))
+	bits := 0
+	for _, fragment := range event.Message.Fragments {
+		images := []types.ChatMessageImage{}
+		var emote *types.ChatMessageEmote
+		if fragment.Type == "emote" && fragment.Emote != nil {
+			images = emotes.TwitchEmoteImages(fragment.Emote.Id, fragment.Emote.Format, nil)
+			emote = &types.ChatMessageEmote{Id: fragment.Emote.Id, Provider: emotes.ProviderTwitch}
+		}
 
-			for _, maybeFormat := range formats {
-				format, valid := maybeFormat.(string)
-				if !valid {
-					return nil, errors.New("emote format contained a bad format not of the correct type")
+		var cheermote *types.ChatMessageCheermote
+		if fragment.Type == "cheermote" && fragment.Cheermote != nil {
+			bits += fragment.Cheermote.Bits
+
+			resolved, cheermoteImages, exists := emotes.ResolveTwitchCheermote(event.BroadcasterUserId, fragment.Cheermote.Prefix, fragment.Cheermote.Bits)
+			if exists {
+				cheermote = resolved
+				images = cheermoteImages
+			} else {
+				cheermote = &types.ChatMessageCheermote{
+					Prefix: fragment.Cheermote.Prefix,
+					Bits:   fragment.Cheermote.Bits,
+					Tier:   fragment.Cheermote.Tier,
 				}
-
-				images = append(
-					images,
-					types.ChatMessageImage{
-						Url: "https://static-cdn.jtvnw.net/emoticons/v2/" + emoteId + "/" + format + "/dark/1.0",
-					},
-					types.ChatMessageImage{
-						Url: "https://static-cdn.jtvnw.net/emoticons/v2/" + emoteId + "/" + format + "/dark/2.0",
-					},
-					types.ChatMessageImage{
-						Url: "https://static-cdn.jtvnw.net/emoticons/v2/" + emoteId + "/" + format + "/dark/3.0",
-					},
-				)
 			}
 		}
 
 		chat_fragments = append(chat_fragments, types.ChatMessageFragment{
-			Type:   fragment_type,
-			Text:   text,
-			Images: images,
+			Type:      fragment.Type,
+			Text:      fragment.Text,
+			Images:    images,
+			Emote:     emote,
+			Cheermote: cheermote,
 		})
 	}
 
 	features := []string{}
-	if message_type != "text" {
-		features = append(features, message_type)
+	if event.MessageType != "text" {
+		features = append(features, event.MessageType)
 	}
 
 	var reply *types.ChatMessageReply
-	reply_data, valid := event["reply"].(map[string]any)
-	if !valid {
-		reply = nil
-	} else {
-		replied_to_message_id, valid := reply_data["parent_message_id"].(string)
-		if !valid {
-			return nil, errors.New("reply parent_message_id was not of the correct type")
-		}
-		replied_to_chatter_id, valid := reply_data["parent_user_id"].(string)
-		if !valid {
-			return nil, errors.New("reply parent_user_id was not of the correct type")
-		}
-		replied_to_chatter_login, valid := reply_data["parent_user_login"].(string)
-		if !valid {
-			return nil, errors.New("reply parent_user_login was not of the correct type")
-		}
-		replied_to_chatter_name, valid := reply_data["parent_user_name"].(string)
-		if !valid {
-			return nil, errors.New("reply parent_user_name was not of the correct type")
-		}
-
-		viewer, _ := viewers.GetViewerByProviderId("twitch", replied_to_chatter_id)
+	if event.Reply != nil {
+		viewer, _ := viewers.GetViewerByProviderId("twitch", event.Reply.ParentUserId)
 
 		reply = &types.ChatMessageReply{
-			RepliedToMessageId: replied_to_message_id,
+			RepliedToMessageId: event.Reply.ParentMessageId,
 			RepliedToChatter: types.Chatter{
-				Username:    replied_to_chatter_login,
-				DisplayName: replied_to_chatter_name,
+				Username:    event.Reply.ParentUserLogin,
+				DisplayName: event.Reply.ParentUserName,
 			},
 			RepliedToViewer: viewer,
 		}
 	}
 
-	viewer, _ := viewers.GetViewerByProviderId("twitch", chatter_user_id)
+	// Emotes come from the channel the message was sent in, which is the source in shared chat
+	emoteChannelId := event.BroadcasterUserId
+	var source *types.Channel
+	if event.SourceBroadcasterUserId != nil && *event.SourceBroadcasterUserId != event.BroadcasterUserId {
+		source = &types.Channel{
+			Id:       *event.SourceBroadcasterUserId,
+			Platform: "twitch",
+		}
+		if event.SourceBroadcasterUserLogin != nil {
+			source.Username = *event.SourceBroadcasterUserLogin
+		}
+		if event.SourceBroadcasterUserName != nil {
+			source.DisplayName = *event.SourceBroadcasterUserName
+		}
+
+		emoteChannelId = source.Id
+		channels.Ensure("twitch", source.Id)
+	}
+
+	chatBadges := make([]types.ChatMessageBadge, 0, len(event.Badges))
+	roles := map[string]bool{}
+	for _, badge := range event.Badges {
+		chatBadges = append(chatBadges, types.ChatMessageBadge{
+			SetId: badge.SetId,
+			Id:    badge.Id,
+			Info:  badge.Info,
+		})
+		roles[badge.SetId] = true
+	}
+
+	// Fall back to the cheer total if the fragments didn't carry the bits
+	if bits == 0 && event.Cheer != nil {
+		bits = event.Cheer.Bits
+	}
+
+	viewer, _ := viewers.GetViewerByProviderId("twitch", event.ChatterUserId)
 
 	return &types.ChatMessage{
-		Id:        message_id,
-		Text:      message_text,
+		Id:        event.MessageId,
+		Text:      event.Message.Text,
 		Reply:     reply,
-		Fragments: emotes.EmotifyFragments("twitch", broadcaster_user_id, chat_fragments),
-		Color:     color,
+		Fragments: emotes.EmotifyFragments("twitch", emoteChannelId, chat_fragments),
+		Color:     event.Color,
 		Channel: types.Channel{
-			Id:          broadcaster_user_id,
-			Username:    broadcaster_user_login,
-			DisplayName: broadcaster_user_name,
+			Id:          event.BroadcasterUserId,
+			Username:    event.BroadcasterUserLogin,
+			DisplayName: event.BroadcasterUserName,
 			Platform:    "twitch",
 		},
+		Source: source,
 		Chatter: types.Chatter{
-			Username:    chatter_user_login,
-			DisplayName: chatter_user_name,
+			Username:    event.ChatterUserLogin,
+			DisplayName: event.ChatterUserName,
 		},
-		Viewer:   viewer,
-		Features: features,
+		Viewer:        viewer,
+		Features:      features,
+		Badges:        badges.ResolveTwitchBadges(event.BroadcasterUserId, chatBadges),
+		IsBroadcaster: roles["broadcaster"],
+		IsModerator:   roles["moderator"] || roles["lead_moderator"],
+		IsVip:         roles["vip"],
+		IsSubscriber:  roles["subscriber"] || roles["founder"],
+		Bits:          bits,
 	}, nil
 }
@@ -18,18 +18,43 @@ func CreateChannelChatMessageDeleteSubscription(broadcasterUserId string, userId
 	}
 }
 
-func ProcessChannelChatMessageDeletePayload(payload map[string]any) (*types.ChatMessageDelete, error) {
-	event, ok := payload["event"].(map[string]any)
-	if !ok {
-		return nil, errors.New("event field is not of the correct type")
-	}
+type ChannelChatMessageDeleteEventV1 struct {
+	BroadcasterUserId    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	TargetUserId         string `json:"target_user_id"`
+	TargetUserLogin      string `json:"target_user_login"`
+	TargetUserName       string `json:"target_user_name"`
+	MessageId            string `json:"message_id"`
+}
+
+func init() {
+	registerSchema(TypeChannelChatMessageDelete, "1", ProcessChannelChatMessageDeleteEvent)
 
-	message_id, ok := event["message_id"].(string)
-	if !ok {
-		return nil, errors.New("message_id field is not of the correct type")
+	Register(Definition{
+		Type:      TypeChannelChatMessageDelete,
+		Version:   "1",
+		EventType: types.EventTypeChatMessageDelete,
+		Scopes:    []string{"user:read:chat"},
+		Bundle:    "chat",
+		Default:   true,
+		Condition: []ConditionField{broadcasterCondition, authorizerUserCondition},
+		Create:    CreateChannelChatMessageDeleteSubscription,
+	})
+}
+
+func ProcessChannelChatMessageDeleteEvent(event *ChannelChatMessageDeleteEventV1) (*types.ChatMessageDelete, error) {
+	if event.MessageId == "" {
+		return nil, errors.New("message_id field is missing")
 	}
 
 	return &types.ChatMessageDelete{
-		Id: message_id,
+		Id: event.MessageId,
+		Channel: types.Channel{
+			Id:          event.BroadcasterUserId,
+			Username:    event.BroadcasterUserLogin,
+			DisplayName: event.BroadcasterUserName,
+			Platform:    "twitch",
+		},
 	}, nil
 }
@@ -0,0 +1,60 @@
+package subscriptions
+
+import (
+	"breakfast/services/events/types"
+	"breakfast/services/viewers"
+)
+
+const TypeChannelFollow = "channel.follow"
+
+func CreateChannelFollowSubscription(broadcasterId string, moderatorId string) SubscriptionConfig {
+	return SubscriptionConfig{
+		Type:    TypeChannelFollow,
+		Version: "2",
+		Condition: map[string]string{
+			"broadcaster_user_id": broadcasterId,
+			"moderator_user_id":   moderatorId,
+		},
+	}
+}
+
+type ChannelFollowEventV2 struct {
+	UserId               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserId    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	FollowedAt           string `json:"followed_at"`
+}
+
+func init() {
+	registerSchema(TypeChannelFollow, "2", ProcessChannelFollowEvent)
+
+	Register(Definition{
+		Type:      TypeChannelFollow,
+		Version:   "2",
+		EventType: types.EventTypeFollow,
+		Scopes:    []string{"moderator:read:followers"},
+		Condition: []ConditionField{broadcasterCondition, authorizerModeratorCondition},
+		Create:    CreateChannelFollowSubscription,
+	})
+}
+
+func ProcessChannelFollowEvent(event *ChannelFollowEventV2) (*types.Follow, error) {
+	viewer, _ := viewers.GetViewerByProviderId("twitch", event.UserId)
+
+	return &types.Follow{
+		Channel: types.Channel{
+			Id:          event.BroadcasterUserId,
+			Username:    event.BroadcasterUserLogin,
+			DisplayName: event.BroadcasterUserName,
+			Platform:    "twitch",
+		},
+		Chatter: types.Chatter{
+			Username:    event.UserLogin,
+			DisplayName: event.UserName,
+		},
+		Viewer: viewer,
+	}, nil
+}
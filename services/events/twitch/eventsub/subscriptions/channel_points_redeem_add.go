@@ -3,7 +3,6 @@ package subscriptions
 import (
 	"breakfast/services/events/types"
 	"breakfast/services/viewers"
-	"errors"
 )
 
 const TypeChannelPointsRedeemAdd = "channel.channel_points_custom_reward_redemption.add"
@@ -18,98 +17,88 @@ func CreateChannelPointsRedeemAddSubscription(twitchBroadcasterId string) Subscr
 	}
 }
 
-func ProcessChannelPointsRedeemAddPayload(payload map[string]any) (*types.CurrencySpent, error) {
-	event, valid := payload["event"].(map[string]any)
-	if !valid {
-		return nil, errors.New("event field was not of the correct type")
-	}
-
-	id, valid := event["id"].(string)
-	if !valid {
-		return nil, errors.New("id was not of the correct type")
-	}
-
-	broadcaster_user_id, valid := event["broadcaster_user_id"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_id was not of the correct type")
-	}
-	broadcaster_user_login, valid := event["broadcaster_user_login"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_login was not of the correct type")
-	}
-	broadcaster_user_name, valid := event["broadcaster_user_name"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_name was not of the correct type")
-	}
+type ChannelPointsRedeemAddEventV1 struct {
+	Id                   string `json:"id"`
+	BroadcasterUserId    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	UserId               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	UserInput            string `json:"user_input"`
+	Status               string `json:"status"`
+	Reward               struct {
+		Id     string `json:"id"`
+		Title  string `json:"title"`
+		Cost   int    `json:"cost"`
+		Prompt string `json:"prompt"`
+	} `json:"reward"`
+	RedeemedAt string `json:"redeemed_at"`
+}
 
-	chatter_user_id, valid := event["user_id"].(string)
-	if !valid {
-		return nil, errors.New("user_id was not of the correct type")
-	}
-	chatter_user_login, valid := event["user_login"].(string)
-	if !valid {
-		return nil, errors.New("user_login was not of the correct type")
-	}
-	chatter_user_name, valid := event["user_name"].(string)
-	if !valid {
-		return nil, errors.New("user_name was not of the correct type")
-	}
+func init() {
+	registerSchema(TypeChannelPointsRedeemAdd, "1", ProcessChannelPointsRedeemAddEvent)
 
-	viewer, _ := viewers.GetViewerByProviderId("twitch", chatter_user_id)
+	Register(Definition{
+		Type:      TypeChannelPointsRedeemAdd,
+		Version:   "1",
+		EventType: types.EventTypeCurrencySpent,
+		Scopes:    []string{"channel:read:redemptions"},
+		Default:   true,
+		Condition: []ConditionField{
+			broadcasterCondition,
+			{Key: "reward_id"},
+		},
+		Create: func(broadcasterId string, _ string) SubscriptionConfig {
+			return CreateChannelPointsRedeemAddSubscription(broadcasterId)
+		},
+		Hooks: []Hook{spendViewerChannelPoints},
+	})
+}
 
-	input, valid := event["user_input"].(string)
-	if !valid {
-		return nil, errors.New("user_input was not of the correct type")
+// Keeps the viewer's channel points wallet in sync with what they've spent. Twitch holds the
+// real balance, so the mirrored one is allowed to go negative.
+func spendViewerChannelPoints(data any) error {
+	spent, ok := data.(*types.CurrencySpent)
+	if !ok || spent.Viewer == nil {
+		return nil
 	}
 
-	reward, valid := event["reward"].(map[string]any)
-	if !valid {
-		return nil, errors.New("reward was not of the correct type")
-	}
+	_, err := viewers.Adjust(spent.Viewer.Id, viewers.WalletChange{
+		Currency: spent.Redeemed.Currency,
+		Amount:   -spent.Redeemed.Cost,
+		Reason:   "Redeemed " + spent.Redeemed.Label,
+		Source:   spent.Id,
+		Actor:    "twitch",
+	})
 
-	redeem_id, valid := reward["id"].(string)
-	if !valid {
-		return nil, errors.New("id was not of the correct type")
-	}
-	redeem_label, valid := reward["title"].(string)
-	if !valid {
-		return nil, errors.New("title was not of the correct type")
-	}
-	redeem_desc, valid := reward["prompt"].(string)
-	if !valid {
-		return nil, errors.New("prompt was not of the correct type")
-	}
-	redeem_cost, valid := reward["cost"].(float64)
-	if !valid {
-		return nil, errors.New("cost was not of the correct type")
-	}
+	return err
+}
 
-	status, valid := event["status"].(string)
-	if !valid {
-		return nil, errors.New("status was not of the correct type")
-	}
+func ProcessChannelPointsRedeemAddEvent(event *ChannelPointsRedeemAddEventV1) (*types.CurrencySpent, error) {
+	viewer, _ := viewers.GetViewerByProviderId("twitch", event.UserId)
 
 	return &types.CurrencySpent{
-		Id: id,
+		Id: event.Id,
 		Channel: types.Channel{
-			Id:          broadcaster_user_id,
-			Username:    broadcaster_user_login,
-			DisplayName: broadcaster_user_name,
+			Id:          event.BroadcasterUserId,
+			Username:    event.BroadcasterUserLogin,
+			DisplayName: event.BroadcasterUserName,
 		},
 		Chatter: types.Chatter{
-			Username:    chatter_user_login,
-			DisplayName: chatter_user_name,
+			Username:    event.UserLogin,
+			DisplayName: event.UserName,
 		},
 		Viewer: viewer,
-		Input:  input,
+		Input:  event.UserInput,
 		Redeemed: types.CurrencySpentRedeem{
-			Id:          redeem_id,
-			Label:       redeem_label,
-			Description: redeem_desc,
+			Id:          event.Reward.Id,
+			Label:       event.Reward.Title,
+			Description: event.Reward.Prompt,
 			Currency:    "channel points",
-			Cost:        int(redeem_cost),
+			Cost:        event.Reward.Cost,
 		},
 		Item:   nil,
-		Status: status,
+		Status: event.Status,
 	}, nil
 }
@@ -0,0 +1,78 @@
+package subscriptions
+
+import (
+	"breakfast/services/events/types"
+	"breakfast/services/viewers"
+	"errors"
+)
+
+const TypeChannelRaid = "channel.raid"
+
+func CreateChannelRaidToSubscription(toBroadcasterId string) SubscriptionConfig {
+	return SubscriptionConfig{
+		Type:    TypeChannelRaid,
+		Version: "1",
+		Condition: map[string]string{
+			"to_broadcaster_user_id": toBroadcasterId,
+		},
+	}
+}
+
+type ChannelRaidEventV1 struct {
+	FromBroadcasterUserId    string `json:"from_broadcaster_user_id"`
+	FromBroadcasterUserLogin string `json:"from_broadcaster_user_login"`
+	FromBroadcasterUserName  string `json:"from_broadcaster_user_name"`
+	ToBroadcasterUserId      string `json:"to_broadcaster_user_id"`
+	ToBroadcasterUserLogin   string `json:"to_broadcaster_user_login"`
+	ToBroadcasterUserName    string `json:"to_broadcaster_user_name"`
+	Viewers                  int    `json:"viewers"`
+}
+
+func init() {
+	registerSchema(TypeChannelRaid, "1", ProcessChannelRaidEvent)
+
+	Register(Definition{
+		Type:      TypeChannelRaid,
+		Version:   "1",
+		EventType: types.EventTypeRaid,
+		Scopes:    []string{},
+		Condition: []ConditionField{
+			{Key: "from_broadcaster_user_id", User: true},
+			{Key: "to_broadcaster_user_id", User: true},
+		},
+		// Twitch only accepts one side of the raid per subscription
+		ValidateCondition: func(condition map[string]string) error {
+			_, from := condition["from_broadcaster_user_id"]
+			_, to := condition["to_broadcaster_user_id"]
+			if from == to {
+				return errors.New("exactly one of from_broadcaster_user_id or to_broadcaster_user_id is required for " + TypeChannelRaid)
+			}
+
+			return nil
+		},
+		Create: func(broadcasterId string, _ string) SubscriptionConfig {
+			return CreateChannelRaidToSubscription(broadcasterId)
+		},
+	})
+}
+
+func ProcessChannelRaidEvent(event *ChannelRaidEventV1) (*types.Raid, error) {
+	viewer, _ := viewers.GetViewerByProviderId("twitch", event.FromBroadcasterUserId)
+
+	return &types.Raid{
+		From: types.Channel{
+			Id:          event.FromBroadcasterUserId,
+			Username:    event.FromBroadcasterUserLogin,
+			DisplayName: event.FromBroadcasterUserName,
+			Platform:    "twitch",
+		},
+		To: types.Channel{
+			Id:          event.ToBroadcasterUserId,
+			Username:    event.ToBroadcasterUserLogin,
+			DisplayName: event.ToBroadcasterUserName,
+			Platform:    "twitch",
+		},
+		Viewer:  viewer,
+		Viewers: event.Viewers,
+	}, nil
+}
@@ -3,7 +3,6 @@ package subscriptions
 import (
 	"breakfast/services/events/types"
 	"breakfast/services/viewers"
-	"errors"
 )
 
 const TypeChannelSubscribe = "channel.subscribe"
@@ -18,67 +17,49 @@ func CreateChannelSubscribeSubscription(broadcasterId string) SubscriptionConfig
 	}
 }
 
-func ProcessChannelSubscribePayload(payload map[string]any) (*types.Subscription, error) {
-	event, ok := payload["event"].(map[string]any)
-	if !ok {
-		return nil, errors.New("event field was not of the correct type")
-	}
-
-	broadcaster_user_id, ok := event["broadcaster_user_id"].(string)
-	if !ok {
-		return nil, errors.New("broabcaster_user_id field is not of the correct type")
-	}
-
-	broadcaster_user_login, ok := event["broadcaster_user_login"].(string)
-	if !ok {
-		return nil, errors.New("broadcaster_user_login field is not of the correct type")
-	}
-
-	broadcaster_user_name, ok := event["broadcaster_user_name"].(string)
-	if !ok {
-		return nil, errors.New("broadcaster_user_name field is not of the correct type")
-	}
-
-	user_id, ok := event["user_id"].(string)
-	if !ok {
-		return nil, errors.New("user_id field was not of the correct type")
-	}
+type ChannelSubscribeEventV1 struct {
+	UserId               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserId    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	Tier                 string `json:"tier"`
+	IsGift               bool   `json:"is_gift"`
+}
 
-	user_login, ok := event["user_login"].(string)
-	if !ok {
-		return nil, errors.New("user_login field was not of the correct type")
-	}
+func init() {
+	registerSchema(TypeChannelSubscribe, "1", ProcessChannelSubscribeEvent)
 
-	user_name, ok := event["user_name"].(string)
-	if !ok {
-		return nil, errors.New("user_name field was not of the correct type")
-	}
-
-	tier, ok := event["tier"].(string)
-	if !ok {
-		return nil, errors.New("tier field was not of the correct type")
-	}
-
-	is_gift, ok := event["is_gift"].(bool)
-	if !ok {
-		return nil, errors.New("is_gift field was not of the correct type")
-	}
+	Register(Definition{
+		Type:      TypeChannelSubscribe,
+		Version:   "1",
+		EventType: types.EventTypeSubscription,
+		Scopes:    []string{"channel:read:subscriptions"},
+		Default:   true,
+		Condition: []ConditionField{broadcasterCondition},
+		Create: func(broadcasterId string, _ string) SubscriptionConfig {
+			return CreateChannelSubscribeSubscription(broadcasterId)
+		},
+	})
+}
 
-	viewer, _ := viewers.GetViewerByProviderId("twitch", user_id)
+func ProcessChannelSubscribeEvent(event *ChannelSubscribeEventV1) (*types.Subscription, error) {
+	viewer, _ := viewers.GetViewerByProviderId("twitch", event.UserId)
 
 	return &types.Subscription{
 		Channel: types.Channel{
-			Id:          broadcaster_user_id,
-			Username:    broadcaster_user_login,
-			DisplayName: broadcaster_user_name,
+			Id:          event.BroadcasterUserId,
+			Username:    event.BroadcasterUserLogin,
+			DisplayName: event.BroadcasterUserName,
 			Platform:    "twitch",
 		},
 		Chatter: types.Chatter{
-			Username:    user_login,
-			DisplayName: user_name,
+			Username:    event.UserLogin,
+			DisplayName: event.UserName,
 		},
 		Viewer: viewer,
-		Gifted: is_gift,
-		Tier:   tier,
+		Gifted: event.IsGift,
+		Tier:   event.Tier,
 	}, nil
 }
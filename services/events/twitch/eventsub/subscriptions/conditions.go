@@ -0,0 +1,97 @@
+package subscriptions
+
+import (
+	"errors"
+	"strings"
+)
+
+const ConditionDefaultAuthorizer = "authorizer"
+
+/*
+Key - the twitch condition key
+Required - whether the condition must have a value
+Default - what to fill the value with when it isn't given, only "authorizer" is supported
+User - the value is a twitch user id and can be given as a login instead (key with _id replaced by _login)
+*/
+type ConditionField struct {
+	Key      string `json:"key"`
+	Required bool   `json:"required"`
+	Default  string `json:"default"`
+	User     bool   `json:"user"`
+}
+
+func (f ConditionField) LoginKey() string {
+	if !f.User || !strings.HasSuffix(f.Key, "_id") {
+		return ""
+	}
+
+	return strings.TrimSuffix(f.Key, "_id") + "_login"
+}
+
+// Resolves a twitch login to a user id
+type LoginResolver func(login string) (string, error)
+
+// Builds a subscription config from user input, validating it against the condition fields
+// of the type. Logins are resolved to ids for user fields and defaults are applied.
+func (d *Definition) BuildConfig(input map[string]string, authorizerId string, resolve LoginResolver) (SubscriptionConfig, error) {
+	allowed := map[string]bool{}
+	for _, field := range d.Condition {
+		allowed[field.Key] = true
+		if login := field.LoginKey(); login != "" {
+			allowed[login] = true
+		}
+	}
+
+	for key := range input {
+		if !allowed[key] {
+			return SubscriptionConfig{}, errors.New(key + " is not a valid condition for " + d.Type)
+		}
+	}
+
+	condition := map[string]string{}
+	for _, field := range d.Condition {
+		value := input[field.Key]
+
+		if login := field.LoginKey(); value == "" && login != "" && input[login] != "" {
+			if resolve == nil {
+				return SubscriptionConfig{}, errors.New("unable to resolve " + login)
+			}
+
+			id, err := resolve(input[login])
+			if err != nil {
+				return SubscriptionConfig{}, errors.Join(errors.New("failed to resolve "+login), err)
+			}
+			value = id
+		}
+
+		if value == "" && field.Default == ConditionDefaultAuthorizer {
+			value = authorizerId
+		}
+
+		if value == "" {
+			if field.Required {
+				return SubscriptionConfig{}, errors.New(field.Key + " is required for " + d.Type)
+			}
+			continue
+		}
+
+		condition[field.Key] = value
+	}
+
+	if d.ValidateCondition != nil {
+		err := d.ValidateCondition(condition)
+		if err != nil {
+			return SubscriptionConfig{}, err
+		}
+	}
+
+	return SubscriptionConfig{
+		Type:      d.Type,
+		Version:   d.Version,
+		Condition: condition,
+	}, nil
+}
+
+var broadcasterCondition = ConditionField{Key: "broadcaster_user_id", Required: true, User: true}
+var authorizerUserCondition = ConditionField{Key: "user_id", Required: true, Default: ConditionDefaultAuthorizer}
+var authorizerModeratorCondition = ConditionField{Key: "moderator_user_id", Required: true, Default: ConditionDefaultAuthorizer}
@@ -0,0 +1,92 @@
+package subscriptions
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// Side effect ran with the processed data of an event before it's emitted
+type Hook func(data any) error
+
+/*
+Type - the twitch eventsub subscription type
+Version - the version used when creating new subscriptions
+EventType - the breakfast event type emitted for notifications
+Scopes - oauth scopes the authorizer needs for twitch to accept the subscription
+Bundle - groups types that are subscribed together through the subscribe api
+Default - whether the type is subscribed for a user's own channel when they link twitch
+Condition - the condition fields accepted when subscribing through the api
+ValidateCondition - extra validation for conditions that can't be expressed by the fields alone
+*/
+type Definition struct {
+	Type              string
+	Version           string
+	EventType         string
+	Scopes            []string
+	Bundle            string
+	Default           bool
+	Condition         []ConditionField
+	ValidateCondition func(condition map[string]string) error
+	Create            func(broadcasterId string, authorizerId string) SubscriptionConfig
+	Hooks             []Hook
+}
+
+var definitions map[string]*Definition = make(map[string]*Definition)
+var definitionOrder []string = []string{}
+
+// Registers a subscription type. Should be called from init after its schemas are registered.
+func Register(definition Definition) {
+	if _, exists := definitions[definition.Type]; exists {
+		panic("subscription type registered multiple times: " + definition.Type)
+	}
+
+	if !IsSupported(definition.Type, definition.Version) {
+		panic("subscription type registered without a schema: " + schemaKey(definition.Type, definition.Version))
+	}
+
+	definitions[definition.Type] = &definition
+	definitionOrder = append(definitionOrder, definition.Type)
+}
+
+func Get(subType string) (*Definition, bool) {
+	definition, exists := definitions[subType]
+	return definition, exists
+}
+
+func All() []*Definition {
+	all := make([]*Definition, 0, len(definitionOrder))
+	for _, subType := range definitionOrder {
+		all = append(all, definitions[subType])
+	}
+
+	return all
+}
+
+func Bundle(name string) []*Definition {
+	bundle := []*Definition{}
+	for _, definition := range All() {
+		if definition.Bundle == name {
+			bundle = append(bundle, definition)
+		}
+	}
+
+	return bundle
+}
+
+// All the scopes needed to create every registered subscription type
+func RequiredScopes() []string {
+	scopes := []string{}
+	for _, definition := range All() {
+		for _, scope := range definition.Scopes {
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return scopes
+}
+
+func (d *Definition) Process(version string, event json.RawMessage) (any, error) {
+	return ProcessPayload(d.Type, version, event)
+}
@@ -0,0 +1,62 @@
+package subscriptions
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var ErrUnsupportedSubscription = errors.New("subscription type and version is not supported")
+
+// Decodes the raw event of a notification and converts it to a breakfast event
+type PayloadProcessor func(event json.RawMessage) (any, error)
+
+var schemas map[string]PayloadProcessor = make(map[string]PayloadProcessor)
+
+func schemaKey(subType string, version string) string {
+	return subType + "@" + version
+}
+
+// Registers the event struct a subscription type and version decodes to, and the
+// converter that turns it into a breakfast event. Should be called from init.
+func registerSchema[T any, R any](subType string, version string, convert func(event *T) (*R, error)) {
+	key := schemaKey(subType, version)
+	if _, exists := schemas[key]; exists {
+		panic("schema registered multiple times: " + key)
+	}
+
+	schemas[key] = func(raw json.RawMessage) (any, error) {
+		if len(raw) == 0 || string(raw) == "null" {
+			return nil, errors.New("event is empty")
+		}
+
+		var event T
+		err := json.Unmarshal(raw, &event)
+		if err != nil {
+			return nil, errors.Join(errors.New("event does not match schema "+key), err)
+		}
+
+		return convert(&event)
+	}
+}
+
+func IsSupported(subType string, version string) bool {
+	_, exists := schemas[schemaKey(subType, version)]
+	return exists
+}
+
+func ValidateConfig(config SubscriptionConfig) error {
+	if !IsSupported(config.Type, config.Version) {
+		return errors.Join(ErrUnsupportedSubscription, errors.New(config.Type+" version "+config.Version))
+	}
+
+	return nil
+}
+
+func ProcessPayload(subType string, version string, event json.RawMessage) (any, error) {
+	process, exists := schemas[schemaKey(subType, version)]
+	if !exists {
+		return nil, ErrUnsupportedSubscription
+	}
+
+	return process(event)
+}
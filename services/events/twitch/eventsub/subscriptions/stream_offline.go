@@ -1,8 +1,10 @@
 package subscriptions
 
 import (
+	"breakfast/services/events/streams"
 	"breakfast/services/events/types"
 	"errors"
+	"time"
 )
 
 const TypeStreamOffline = "stream.offline"
@@ -17,27 +19,49 @@ func CreateStreamOfflineSubscription(twitch_broadcaster_id string) SubscriptionC
 	}
 }
 
-func ProcessStreamOfflinePayload(payload map[string]any) (*types.StreamOffline, error) {
-	broadcaster_user_id, valid := payload["broadcaster_user_id"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_id field was not of the correct type")
-	}
+type StreamOfflineEventV1 struct {
+	BroadcasterUserId    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+}
+
+func init() {
+	registerSchema(TypeStreamOffline, "1", ProcessStreamOfflineEvent)
+
+	Register(Definition{
+		Type:      TypeStreamOffline,
+		Version:   "1",
+		EventType: types.EventTypeStreamOffline,
+		Scopes:    []string{},
+		Bundle:    "live",
+		Default:   true,
+		Condition: []ConditionField{broadcasterCondition},
+		Create: func(broadcasterId string, _ string) SubscriptionConfig {
+			return CreateStreamOfflineSubscription(broadcasterId)
+		},
+		Hooks: []Hook{endStreamSession},
+	})
+}
 
-	broadcaster_user_login, valid := payload["broadcaster_user_login"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_login field was not of the correct type")
+func endStreamSession(data any) error {
+	offline, ok := data.(*types.StreamOffline)
+	if !ok {
+		return nil
 	}
 
-	broadcaster_user_name, valid := payload["broadcaster_user_name"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_name field was not of the correct type")
+	return streams.End(offline.Channel.Platform, offline.Channel.Id, time.Now().UTC())
+}
+
+func ProcessStreamOfflineEvent(event *StreamOfflineEventV1) (*types.StreamOffline, error) {
+	if event.BroadcasterUserId == "" {
+		return nil, errors.New("broadcaster_user_id field is missing")
 	}
 
 	return &types.StreamOffline{
 		Channel: types.Channel{
-			Id:          broadcaster_user_id,
-			Username:    broadcaster_user_login,
-			DisplayName: broadcaster_user_name,
+			Id:          event.BroadcasterUserId,
+			Username:    event.BroadcasterUserLogin,
+			DisplayName: event.BroadcasterUserName,
 			Platform:    "twitch",
 		},
 	}, nil
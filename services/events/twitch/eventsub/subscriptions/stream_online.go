@@ -1,8 +1,10 @@
 package subscriptions
 
 import (
+	"breakfast/services/events/streams"
 	"breakfast/services/events/types"
 	"errors"
+	"time"
 )
 
 const TypeStreamOnline = "stream.online"
@@ -17,33 +19,60 @@ func CreateStreamOnlineSubscription(twitch_broadcaster_id string) SubscriptionCo
 	}
 }
 
-func ProcessStreamOnlinePayload(payload map[string]any) (*types.StreamOnline, error) {
-	event, ok := payload["event"].(map[string]any)
+type StreamOnlineEventV1 struct {
+	Id                   string `json:"id"`
+	BroadcasterUserId    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	Type                 string `json:"type"`
+	StartedAt            string `json:"started_at"`
+}
+
+func init() {
+	registerSchema(TypeStreamOnline, "1", ProcessStreamOnlineEvent)
+
+	Register(Definition{
+		Type:      TypeStreamOnline,
+		Version:   "1",
+		EventType: types.EventTypeStreamOnline,
+		Scopes:    []string{},
+		Bundle:    "live",
+		Default:   true,
+		Condition: []ConditionField{broadcasterCondition},
+		Create: func(broadcasterId string, _ string) SubscriptionConfig {
+			return CreateStreamOnlineSubscription(broadcasterId)
+		},
+		Hooks: []Hook{startStreamSession},
+	})
+}
+
+func startStreamSession(data any) error {
+	online, ok := data.(*types.StreamOnline)
 	if !ok {
-		return nil, errors.New("event field was not of the correct type")
+		return nil
 	}
 
-	broadcaster_user_id, valid := payload["broadcaster_user_id"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_id field was not of the correct type")
-	}
+	_, err := streams.Start(online.Channel.Platform, online.Channel.Id, online.StartedAt)
+	return err
+}
 
-	broadcaster_user_login, valid := event["broadcaster_user_login"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_login field was not of the correct type")
+func ProcessStreamOnlineEvent(event *StreamOnlineEventV1) (*types.StreamOnline, error) {
+	if event.BroadcasterUserId == "" {
+		return nil, errors.New("broadcaster_user_id field is missing")
 	}
 
-	broadcaster_user_name, valid := event["broadcaster_user_name"].(string)
-	if !valid {
-		return nil, errors.New("broadcaster_user_name field was not of the correct type")
+	startedAt, err := time.Parse(time.RFC3339, event.StartedAt)
+	if err != nil {
+		startedAt = time.Now()
 	}
 
 	return &types.StreamOnline{
 		Channel: types.Channel{
-			Id:          broadcaster_user_id,
-			Username:    broadcaster_user_login,
-			DisplayName: broadcaster_user_name,
+			Id:          event.BroadcasterUserId,
+			Username:    event.BroadcasterUserLogin,
+			DisplayName: event.BroadcasterUserName,
 			Platform:    "twitch",
 		},
+		StartedAt: startedAt.UTC(),
 	}, nil
 }
@@ -4,8 +4,13 @@ import (
 	"breakfast/services/events/twitch/eventsub"
 
 	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/cron"
 )
 
 func RegisterService(app *pocketbase.PocketBase) {
 	eventsub.RegisterService(app)
 }
+
+func RegisterJobs(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	eventsub.RegisterJobs(app, scheduler)
+}
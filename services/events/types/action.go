@@ -1,8 +1,16 @@
 package types
 
+import "breakfast/services/viewers"
+
+/*
+Viewer - the viewer whose item ran the action, nil for actions sent from the dashboard
+Item - the item that ran the action, nil for actions sent from the dashboard
+*/
 type Action struct {
 	Type   string          `json:"type"`
 	Emit   string          `json:"emit"`
 	Inputs map[string]any  `json:"inputs"`
 	Event  *BreakfastEvent `json:"event"`
+	Viewer *viewers.Viewer `json:"viewer,omitempty"`
+	Item   *Item           `json:"item,omitempty"`
 }
@@ -2,13 +2,72 @@ package types
 
 import "breakfast/services/viewers"
 
+const ImageFormatWebp = "webp"
+const ImageFormatAvif = "avif"
+const ImageFormatGif = "gif"
+const ImageFormatPng = "png"
+
+/*
+Width - width in pixels, 0 when the provider doesn't say
+Height - height in pixels, 0 when the provider doesn't say
+Scale - size relative to the smallest image of the emote (1, 2, 3 or 4)
+Format - one of webp, avif, gif or png
+Theme - dark or light for images made for a chat theme, empty when the image isn't themed
+*/
 type ChatMessageImage struct {
-	Url string `json:"url"`
+	Url      string  `json:"url"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+	Scale    float64 `json:"scale"`
+	Format   string  `json:"format"`
+	Animated bool    `json:"animated"`
+	Theme    string  `json:"theme"`
+}
+
+/*
+Tier - the minimum bits of the tier the cheer reached
+Color - the color of the tier as a hex code
+*/
+type ChatMessageCheermote struct {
+	Prefix string `json:"prefix"`
+	Bits   int    `json:"bits"`
+	Tier   int    `json:"tier"`
+	Color  string `json:"color"`
 }
 
+/*
+Id - the emote's id with its provider
+Provider - where the emote comes from, e.g. twitch, 7tv, bttv or ffz
+*/
+type ChatMessageEmote struct {
+	Id       string `json:"id"`
+	Provider string `json:"provider"`
+}
+
+/*
+Emote - which emote an emote fragment is, nil for other fragments
+Overlays - zero width emotes stacked on top of this emote, in the order they're drawn
+Cheermote - details of the cheer for cheermote fragments
+*/
 type ChatMessageFragment struct {
-	Type   string             `json:"type"`
-	Text   string             `json:"text"`
+	Type      string                `json:"type"`
+	Text      string                `json:"text"`
+	Images    []ChatMessageImage    `json:"images"`
+	Emote     *ChatMessageEmote     `json:"emote"`
+	Overlays  []ChatMessageFragment `json:"overlays"`
+	Cheermote *ChatMessageCheermote `json:"cheermote"`
+}
+
+/*
+SetId - the badge set, e.g. subscriber or moderator
+Id - the version within the set, e.g. the number of months for subscriber badges
+Info - extra details about the badge, e.g. the exact number of months subscribed
+*/
+type ChatMessageBadge struct {
+	SetId  string             `json:"setId"`
+	Id     string             `json:"id"`
+	Info   string             `json:"info"`
+	Title  string             `json:"title"`
 	Images []ChatMessageImage `json:"images"`
 }
 
@@ -21,6 +80,7 @@ type ChatMessageReply struct {
 type ChatMessage struct {
 	Id        string                `json:"id"`
 	Channel   Channel               `json:"channel"`
+	Source    *Channel              `json:"source"`
 	Chatter   Chatter               `json:"chatter"`
 	Viewer    *viewers.Viewer       `json:"viewer"`
 	Reply     *ChatMessageReply     `json:"reply"`
@@ -28,8 +88,29 @@ type ChatMessage struct {
 	Color     string                `json:"color"`
 	Fragments []ChatMessageFragment `json:"fragments"`
 	Features  []string              `json:"features"`
+	Badges    []ChatMessageBadge    `json:"badges"`
+	// Total bits cheered in the message
+	Bits int `json:"bits"`
+	// Roles derived from the chatter's badges
+	IsBroadcaster bool `json:"isBroadcaster"`
+	IsModerator   bool `json:"isModerator"`
+	IsVip         bool `json:"isVip"`
+	IsSubscriber  bool `json:"isSubscriber"`
+}
+
+func (m *ChatMessage) EventChannels() []Channel {
+	if m.Source != nil {
+		return []Channel{m.Channel, *m.Source}
+	}
+
+	return []Channel{m.Channel}
 }
 
 type ChatMessageDelete struct {
-	Id string `json:"id"`
+	Id      string  `json:"id"`
+	Channel Channel `json:"channel"`
+}
+
+func (d *ChatMessageDelete) EventChannels() []Channel {
+	return []Channel{d.Channel}
 }
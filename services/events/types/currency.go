@@ -24,3 +24,7 @@ type CurrencySpent struct {
 	Item     *any                `json:"item"`
 	Status   string              `json:"status"`
 }
+
+func (c *CurrencySpent) EventChannels() []Channel {
+	return []Channel{c.Channel}
+}
@@ -0,0 +1,17 @@
+package types
+
+/*
+Provider - the emote provider that changed (e.g. 7tv)
+Added - names of emotes added to the channel, renamed emotes are listed under their new name
+Removed - names of emotes removed from the channel, renamed emotes are listed under their old name
+*/
+type EmotesUpdated struct {
+	Channel  Channel  `json:"channel"`
+	Provider string   `json:"provider"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+}
+
+func (e *EmotesUpdated) EventChannels() []Channel {
+	return []Channel{e.Channel}
+}
@@ -1,5 +1,7 @@
 package types
 
+import "strings"
+
 const BreakfastEventsKey = "@breakfast/events"
 
 type BreakfastEvent struct {
@@ -8,3 +10,32 @@ type BreakfastEvent struct {
 	Platform string  `json:"platform"`
 	Data     any     `json:"data"`
 }
+
+// Implemented by event data that happened in specific channels so listeners can filter by channel
+type ChannelScoped interface {
+	EventChannels() []Channel
+}
+
+// Checks if the event data happened in a channel matching one of the ids or usernames. Data that
+// isn't scoped to a channel always matches.
+func MatchesChannels(data any, channels []string) bool {
+	scoped, ok := data.(ChannelScoped)
+	if !ok || len(channels) == 0 {
+		return true
+	}
+
+	eventChannels := scoped.EventChannels()
+	if len(eventChannels) == 0 {
+		return true
+	}
+
+	for _, channel := range eventChannels {
+		for _, match := range channels {
+			if channel.Id == match || strings.EqualFold(channel.Username, match) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
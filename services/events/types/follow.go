@@ -0,0 +1,13 @@
+package types
+
+import "breakfast/services/viewers"
+
+type Follow struct {
+	Channel Channel         `json:"channel"`
+	Chatter Chatter         `json:"chatter"`
+	Viewer  *viewers.Viewer `json:"viewer"`
+}
+
+func (f *Follow) EventChannels() []Channel {
+	return []Channel{f.Channel}
+}
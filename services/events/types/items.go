@@ -0,0 +1,86 @@
+package types
+
+import "breakfast/services/viewers"
+
+/*
+Image - the path of the item's image file, empty when it has none
+*/
+type Item struct {
+	Id          string `json:"id"`
+	Type        string `json:"type"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	Image       string `json:"image"`
+}
+
+/*
+Id - the id of the purchase record
+Channel - the channel the item was bought from through chat, nil when bought through the api
+Price - what was paid in the currency, 0 and no currency for free items
+*/
+type ItemPurchased struct {
+	Id       string          `json:"id"`
+	Channel  *Channel        `json:"channel"`
+	Viewer   *viewers.Viewer `json:"viewer"`
+	Item     Item            `json:"item"`
+	Currency string          `json:"currency"`
+	Price    int             `json:"price"`
+}
+
+func (p *ItemPurchased) EventChannels() []Channel {
+	if p.Channel == nil {
+		return []Channel{}
+	}
+
+	return []Channel{*p.Channel}
+}
+
+/*
+Id - the id of the ledger record of the grant
+Quantity - how many of the item were given
+*/
+type ItemGranted struct {
+	Id       string          `json:"id"`
+	Viewer   *viewers.Viewer `json:"viewer"`
+	Item     Item            `json:"item"`
+	Quantity int             `json:"quantity"`
+}
+
+type TradedItem struct {
+	Item     Item `json:"item"`
+	Quantity int  `json:"quantity"`
+}
+
+/*
+Id - the id of the trade record
+Sender - the viewer that offered the trade
+Recipient - the viewer that accepted the trade
+Offered - what the sender gave, empty when the sender only asked for items
+Requested - what the recipient gave, empty for gifts
+*/
+type ItemTraded struct {
+	Id        string          `json:"id"`
+	Sender    *viewers.Viewer `json:"sender"`
+	Recipient *viewers.Viewer `json:"recipient"`
+	Offered   []TradedItem    `json:"offered"`
+	Requested []TradedItem    `json:"requested"`
+}
+
+/*
+Id - the id of the loot drop record, which keeps the seed and loot table to replay the draw
+LootBox - the loot box that was opened
+Item - the item that dropped from it
+Rarity - the rarity of the drop
+Pity - the rarity the draw was limited to after too many boxes without it, empty for normal draws
+Seed - the seed of the draw
+*/
+type LootBoxOpened struct {
+	Id       string          `json:"id"`
+	Viewer   *viewers.Viewer `json:"viewer"`
+	LootBox  Item            `json:"lootBox"`
+	Item     Item            `json:"item"`
+	Quantity int             `json:"quantity"`
+	Rarity   string          `json:"rarity"`
+	Pity     string          `json:"pity"`
+	Seed     string          `json:"seed"`
+}
@@ -0,0 +1,19 @@
+package types
+
+import "breakfast/services/viewers"
+
+/*
+From - the channel that raided
+To - the channel that was raided
+Viewer - the viewer of the raiding broadcaster
+*/
+type Raid struct {
+	From    Channel         `json:"from"`
+	To      Channel         `json:"to"`
+	Viewer  *viewers.Viewer `json:"viewer"`
+	Viewers int             `json:"viewers"`
+}
+
+func (r *Raid) EventChannels() []Channel {
+	return []Channel{r.From, r.To}
+}
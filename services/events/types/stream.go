@@ -1,9 +1,20 @@
 package types
 
+import "time"
+
 type StreamOnline struct {
-	Channel Channel `json:"channel"`
+	Channel   Channel   `json:"channel"`
+	StartedAt time.Time `json:"startedAt"`
 }
 
 type StreamOffline struct {
 	Channel Channel `json:"channel"`
 }
+
+func (s *StreamOnline) EventChannels() []Channel {
+	return []Channel{s.Channel}
+}
+
+func (s *StreamOffline) EventChannels() []Channel {
+	return []Channel{s.Channel}
+}
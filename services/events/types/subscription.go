@@ -17,3 +17,11 @@ type GiftedSubscription struct {
 	Tier    string          `json:"tier"`
 	Total   int             `json:"total"`
 }
+
+func (s *Subscription) EventChannels() []Channel {
+	return []Channel{s.Channel}
+}
+
+func (s *GiftedSubscription) EventChannels() []Channel {
+	return []Channel{s.Channel}
+}
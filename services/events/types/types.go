@@ -4,6 +4,13 @@ const EventTypeAction = "action"
 const EventTypeChatMessage = "chat-message"
 const EventTypeChatMessageDelete = "chat-message-delete"
 const EventTypeCurrencySpent = "currency-spent"
+const EventTypeEmotesUpdated = "emotes-updated"
+const EventTypeFollow = "follow"
+const EventTypeItemGranted = "item-granted"
+const EventTypeItemPurchased = "item-purchased"
+const EventTypeItemTraded = "item-traded"
+const EventTypeLootBoxOpened = "loot-box-opened"
+const EventTypeRaid = "raid"
 const EventTypeStreamOffline = "stream-offline"
 const EventTypeStreamOnline = "stream-online"
 const EventTypeSubscription = "subscription"
@@ -13,6 +20,13 @@ var AllEventTypes = []string{
 	EventTypeChatMessage,
 	EventTypeChatMessageDelete,
 	EventTypeCurrencySpent,
+	EventTypeEmotesUpdated,
+	EventTypeFollow,
+	EventTypeItemGranted,
+	EventTypeItemPurchased,
+	EventTypeItemTraded,
+	EventTypeLootBoxOpened,
+	EventTypeRaid,
 	EventTypeStreamOffline,
 	EventTypeStreamOnline,
 	EventTypeSubscription,
@@ -20,6 +34,12 @@ var AllEventTypes = []string{
 var DefaultSavedEventTypes = []string{
 	EventTypeAction,
 	EventTypeCurrencySpent,
+	EventTypeFollow,
+	EventTypeItemGranted,
+	EventTypeItemPurchased,
+	EventTypeItemTraded,
+	EventTypeLootBoxOpened,
+	EventTypeRaid,
 	EventTypeStreamOffline,
 	EventTypeStreamOnline,
 	EventTypeSubscription,
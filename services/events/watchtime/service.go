@@ -0,0 +1,47 @@
+package watchtime
+
+import (
+	"breakfast/services/viewers"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/cron"
+)
+
+func RegisterService(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/api/breakfast/viewers/:id/watch-time", func(c echo.Context) error {
+			// Validate user is authenticated, viewers can see their own watch time
+			if !viewers.HasPermission(c, c.PathParam("id"), viewers.PermissionViewer) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			sessions, err := GetViewerWatchTime(c.PathParam("id"))
+			if err != nil {
+				return c.JSON(500, map[string]string{"message": "Failed to get watch time", "error": err.Error()})
+			}
+
+			total := 0
+			for _, session := range sessions {
+				total += session.Seconds
+			}
+
+			return c.JSON(200, map[string]any{
+				"total":    total,
+				"sessions": sessions,
+			})
+		})
+
+		return nil
+	})
+}
+
+func RegisterJobs(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		ScheduleChattersPoll(app, scheduler)
+
+		return nil
+	})
+}
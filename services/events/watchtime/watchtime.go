@@ -0,0 +1,137 @@
+package watchtime
+
+import (
+	"breakfast/services"
+	"breakfast/services/apis"
+	"breakfast/services/events/channels"
+	"breakfast/services/events/earnings"
+	"breakfast/services/events/streams"
+	"breakfast/services/viewers"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/tools/cron"
+	pbTypes "github.com/pocketbase/pocketbase/tools/types"
+)
+
+// How often chatters are polled, each poll counts as this much time watched
+const PollInterval = time.Minute
+
+/*
+Seconds - total time the viewer was seen in chat during the session
+FirstSeen - when the viewer was first seen in the session
+LastSeen - when the viewer was last seen in the session
+*/
+type SessionWatchTime struct {
+	Session   string `db:"session" json:"session"`
+	Viewer    string `db:"viewer" json:"viewer"`
+	Seconds   int    `db:"seconds" json:"seconds"`
+	FirstSeen string `db:"firstSeen" json:"firstSeen"`
+	LastSeen  string `db:"lastSeen" json:"lastSeen"`
+}
+
+// Adds a poll interval of watch time for everyone in a live channel's chat
+func pollSession(channel channels.WatchedChannel, session streams.Session) error {
+	if channel.Provider != "twitch" {
+		return nil
+	}
+
+	chatters, err := apis.GetTwitchChatters(channel.ProviderId)
+	if err != nil {
+		return err
+	}
+
+	viewerIds := make([]string, 0, len(chatters))
+	for _, chatter := range chatters {
+		viewer, err := viewers.GetViewerByProviderId("twitch", chatter.UserId)
+		if err != nil {
+			services.App.Logger().Error(
+				"WATCHTIME Failed to get viewer for chatter",
+				"twitchId", chatter.UserId,
+				"error", err.Error(),
+			)
+			continue
+		}
+
+		viewerIds = append(viewerIds, viewer.Id)
+	}
+
+	now := time.Now().UTC().Format(pbTypes.DefaultDateLayout)
+
+	{
+		err := services.App.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			for _, viewerId := range viewerIds {
+				_, err := txDao.DB().
+					NewQuery(`INSERT INTO viewer_watch_time (session, viewer, seconds, firstSeen, lastSeen)
+						VALUES ({:session}, {:viewer}, {:seconds}, {:now}, {:now})
+						ON CONFLICT (session, viewer)
+						DO UPDATE SET seconds = seconds + excluded.seconds, lastSeen = excluded.lastSeen`).
+					Bind(dbx.Params{
+						"session": session.Id,
+						"viewer":  viewerId,
+						"seconds": int(PollInterval.Seconds()),
+						"now":     now,
+					}).
+					Execute()
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Chatters count as watching for the currencies earned per minute watched
+	for _, viewerId := range viewerIds {
+		earnings.MarkWatching(channel.Id, viewerId)
+	}
+
+	return nil
+}
+
+// Polls the chatters of every live channel
+func PollLiveChannels() {
+	for _, session := range streams.AllLive() {
+		channel, exists := channels.GetById(session.Channel)
+		if !exists {
+			continue
+		}
+
+		err := pollSession(channel, session)
+		if err != nil {
+			services.App.Logger().Error(
+				"WATCHTIME Failed to poll chatters",
+				"channel", channel.Username,
+				"session", session.Id,
+				"error", err.Error(),
+			)
+		}
+	}
+}
+
+// Gets a viewer's watch time in each session they were seen in, newest first
+func GetViewerWatchTime(viewerId string) ([]SessionWatchTime, error) {
+	watchTime := []SessionWatchTime{}
+
+	err := services.App.Dao().DB().
+		Select("session", "viewer", "seconds", "firstSeen", "lastSeen").
+		From("viewer_watch_time").
+		Where(dbx.HashExp{"viewer": viewerId}).
+		OrderBy("firstSeen DESC").
+		All(&watchTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return watchTime, nil
+}
+
+func ScheduleChattersPoll(app *pocketbase.PocketBase, scheduler *cron.Cron) {
+	scheduler.MustAdd("watchtime-poll-chatters", "* * * * *", PollLiveChannels)
+}
@@ -0,0 +1,51 @@
+package items
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/types"
+	"strings"
+)
+
+const UseCommand = "!use"
+
+// Uses an item for a chatter that sends "!use <item label>"
+func handleUseCommand(provider string, providerId string, event types.BreakfastEvent) {
+	message, ok := event.Data.(*types.ChatMessage)
+	if !ok || message.Viewer == nil || message.Source != nil {
+		return
+	}
+
+	command, label, found := strings.Cut(strings.TrimSpace(message.Text), " ")
+	if !found || !strings.EqualFold(command, UseCommand) {
+		return
+	}
+
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return
+	}
+
+	viewerItemId, err := FindOwnedItemByLabel(message.Viewer.Id, label)
+	if err != nil {
+		services.App.Logger().Debug(
+			"ITEMS Chatter tried to use an item they don't have",
+			"viewer", message.Viewer.Id,
+			"label", label,
+		)
+		return
+	}
+
+	_, err = Redeem(UseRequest{
+		ViewerId:     message.Viewer.Id,
+		ViewerItemId: viewerItemId,
+		Actor:        "viewer:" + message.Viewer.Id,
+	})
+	if err != nil {
+		services.App.Logger().Info(
+			"ITEMS Chat item use failed",
+			"viewer", message.Viewer.Id,
+			"viewerItem", viewerItemId,
+			"error", err.Error(),
+		)
+	}
+}
@@ -0,0 +1,310 @@
+package items
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/listener"
+	"breakfast/services/events/types"
+	"breakfast/services/viewers"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+// Items that don't stack get a row each, so only so many can be given at once
+const MaxUnstackedGrant = 100
+
+var ErrInvalidQuantity = errors.New("quantity must be more than zero")
+var ErrTooManyUnstacked = errors.New("items that don't stack can't be given that many at once")
+var ErrNotEnoughItems = errors.New("viewer doesn't have enough of the item")
+
+/*
+Quantity - how many of the item to give or take
+Reason - why the items were given or taken, shown in the viewer's history
+Source - the id of what caused the change, e.g. "trade:<id>"
+Actor - who made the change, e.g. "user:<id>" for a streamer
+*/
+type GrantRequest struct {
+	ViewerId string
+	ItemId   string
+	Quantity int
+	Reason   string
+	Source   string
+	Actor    string
+}
+
+// Gives a viewer items inside a transaction and records it in the ledger. Stackable items are
+// added to the viewer's stack, other items get a viewer item each. Returns the ids of the
+// viewer items that were added to and the id of the ledger record.
+func GrantTx(txDao *daos.Dao, request GrantRequest) ([]string, string, error) {
+	if request.Quantity <= 0 {
+		return nil, "", ErrInvalidQuantity
+	}
+
+	itemRecord, err := txDao.FindRecordById("items", request.ItemId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	viewerItemIds := []string{}
+	if itemRecord.GetBool("stackable") {
+		var stack struct {
+			Id string `db:"id"`
+		}
+
+		err := txDao.DB().
+			Select("id").
+			From("viewer_items").
+			Where(dbx.HashExp{"owner": request.ViewerId, "item": itemRecord.Id}).
+			OrderBy("created ASC", "id ASC").
+			Limit(1).
+			One(&stack)
+
+		switch {
+		case err == nil:
+			_, err := txDao.DB().
+				NewQuery("UPDATE viewer_items SET quantity = quantity + {:quantity}, updated = {:updated} WHERE id = {:id}").
+				Bind(dbx.Params{
+					"quantity": request.Quantity,
+					"updated":  time.Now(),
+					"id":       stack.Id,
+				}).
+				Execute()
+			if err != nil {
+				return nil, "", err
+			}
+
+			viewerItemIds = append(viewerItemIds, stack.Id)
+		case errors.Is(err, sql.ErrNoRows):
+			id, err := insertViewerItem(txDao, request.ViewerId, itemRecord.Id, request.Quantity)
+			if err != nil {
+				return nil, "", err
+			}
+
+			viewerItemIds = append(viewerItemIds, id)
+		default:
+			return nil, "", err
+		}
+	} else {
+		if request.Quantity > MaxUnstackedGrant {
+			return nil, "", ErrTooManyUnstacked
+		}
+
+		for i := 0; i < request.Quantity; i++ {
+			id, err := insertViewerItem(txDao, request.ViewerId, itemRecord.Id, 1)
+			if err != nil {
+				return nil, "", err
+			}
+
+			viewerItemIds = append(viewerItemIds, id)
+		}
+	}
+
+	if itemRecord.GetString("type") == "PROFILE_BASE" {
+		err := viewers.EnsureBaseEquipped(txDao, request.ViewerId)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Giving a base can equip it, changing what the viewer's profile draws
+	if strings.HasPrefix(itemRecord.GetString("type"), "PROFILE_") {
+		viewers.InvalidateRenderedLoadout(request.ViewerId)
+	}
+
+	ledgerId, err := RecordItemChange(txDao, ItemChange{
+		ViewerId: request.ViewerId,
+		ItemId:   itemRecord.Id,
+		Delta:    request.Quantity,
+		Reason:   request.Reason,
+		Source:   request.Source,
+		Actor:    request.Actor,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return viewerItemIds, ledgerId, nil
+}
+
+func insertViewerItem(txDao *daos.Dao, viewerId string, itemId string, quantity int) (string, error) {
+	id := security.RandomString(15)
+
+	_, err := txDao.DB().
+		Insert(
+			"viewer_items",
+			dbx.Params{
+				"id":       id,
+				"owner":    viewerId,
+				"item":     itemId,
+				"quantity": quantity,
+				"meta":     nil,
+				"created":  time.Now(),
+				"updated":  time.Now(),
+			},
+		).
+		Execute()
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Takes items from a viewer inside a transaction and records it in the ledger. Unequipped items
+// are taken before equipped ones. Fails with ErrNotEnoughItems without taking anything when the
+// viewer has fewer than the quantity. Returns the id of the ledger record.
+func RevokeTx(txDao *daos.Dao, request GrantRequest) (string, error) {
+	if request.Quantity <= 0 {
+		return "", ErrInvalidQuantity
+	}
+
+	itemRecord, err := txDao.FindRecordById("items", request.ItemId)
+	if err != nil {
+		return "", err
+	}
+
+	var owned []struct {
+		Id       string `db:"id"`
+		Quantity int    `db:"quantity"`
+	}
+
+	{
+		err := txDao.DB().
+			Select("id", "quantity").
+			From("viewer_items").
+			Where(dbx.HashExp{"owner": request.ViewerId, "item": itemRecord.Id}).
+			OrderBy("COALESCE(json_extract(meta, '$.equipped'), FALSE) ASC", "created ASC", "id ASC").
+			All(&owned)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	total := 0
+	for _, row := range owned {
+		total += row.Quantity
+	}
+
+	if total < request.Quantity {
+		return "", ErrNotEnoughItems
+	}
+
+	remaining := request.Quantity
+	for _, row := range owned {
+		if remaining == 0 {
+			break
+		}
+
+		if row.Quantity > remaining {
+			_, err := txDao.DB().
+				NewQuery("UPDATE viewer_items SET quantity = quantity - {:quantity}, updated = {:updated} WHERE id = {:id}").
+				Bind(dbx.Params{
+					"quantity": remaining,
+					"updated":  time.Now(),
+					"id":       row.Id,
+				}).
+				Execute()
+			if err != nil {
+				return "", err
+			}
+
+			remaining = 0
+			break
+		}
+
+		_, err := txDao.DB().
+			Delete("viewer_items", dbx.HashExp{"id": row.Id}).
+			Execute()
+		if err != nil {
+			return "", err
+		}
+
+		remaining -= row.Quantity
+	}
+
+	if itemRecord.GetString("type") == "PROFILE_BASE" {
+		err := viewers.EnsureBaseEquipped(txDao, request.ViewerId)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Taking an equipped profile item changes what the viewer's profile draws
+	if strings.HasPrefix(itemRecord.GetString("type"), "PROFILE_") {
+		viewers.InvalidateRenderedLoadout(request.ViewerId)
+	}
+
+	return RecordItemChange(txDao, ItemChange{
+		ViewerId: request.ViewerId,
+		ItemId:   itemRecord.Id,
+		Delta:    -request.Quantity,
+		Reason:   request.Reason,
+		Source:   request.Source,
+		Actor:    request.Actor,
+	})
+}
+
+// Gives a viewer items and emits an item-granted event, along with the item's action for items
+// that can't be used
+func Grant(request GrantRequest) (*types.ItemGranted, error) {
+	granted := types.ItemGranted{Quantity: request.Quantity}
+	var grantAction *types.Action
+
+	err := services.App.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		_, ledgerId, err := GrantTx(txDao, request)
+		if err != nil {
+			return err
+		}
+
+		itemRecord, err := txDao.FindRecordById("items", request.ItemId)
+		if err != nil {
+			return err
+		}
+
+		granted.Id = ledgerId
+		granted.Item = ItemFromRecord(itemRecord)
+
+		// Items that can't be used run their action when they're given instead
+		if !IsUsable(itemRecord.GetString("type")) {
+			action, err := ActionFromRecord(itemRecord)
+			if err == nil {
+				grantAction = &action
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	viewer, err := viewers.GetViewerById(request.ViewerId)
+	if err == nil {
+		granted.Viewer = viewer
+	}
+
+	listener.EmitEvent("items", granted.Id, types.BreakfastEvent{
+		Type:     types.EventTypeItemGranted,
+		Platform: "items",
+		Data:     &granted,
+	})
+
+	if grantAction != nil {
+		EmitItemAction(granted.Id, grantAction, request.ViewerId, granted.Item)
+	}
+
+	return &granted, nil
+}
+
+// Takes items from a viewer
+func Revoke(request GrantRequest) error {
+	return services.App.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		_, err := RevokeTx(txDao, request)
+		return err
+	})
+}
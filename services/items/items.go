@@ -0,0 +1,229 @@
+package items
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/listener"
+	"breakfast/services/events/types"
+	"breakfast/services/viewers"
+	"errors"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+var ErrNotUsable = errors.New("only consumable and collectable items can be used")
+var ErrNoAction = errors.New("item doesn't have an action")
+var ErrUseLootBox = errors.New("loot boxes are opened rather than used")
+
+func ItemFromRecord(record *models.Record) types.Item {
+	image := ""
+	if file := record.GetString("image"); file != "" {
+		image = "/api/files/items/" + record.Id + "/" + file
+	}
+
+	return types.Item{
+		Id:          record.Id,
+		Type:        record.GetString("type"),
+		Label:       record.GetString("label"),
+		Description: record.GetString("description"),
+		Image:       image,
+	}
+}
+
+// Consumables are used up when used, collectables can be used again and again
+func IsUsable(itemType string) bool {
+	return itemType == "CONSUMABLE" || itemType == "COLLECTABLE"
+}
+
+// Reads the action of an item, returning ErrNoAction when it doesn't have one
+func ActionFromRecord(record *models.Record) (types.Action, error) {
+	var action types.Action
+	if value := record.GetString("action"); value == "" || value == "null" {
+		return action, ErrNoAction
+	}
+
+	{
+		err := record.UnmarshalJSONField("action", &action)
+		if err != nil {
+			return action, err
+		}
+	}
+
+	if action.Type == "" {
+		return action, ErrNoAction
+	}
+
+	return action, nil
+}
+
+// Sends an item's action to overlays with the viewer and item attached. The id is used as the
+// event id, e.g. the ledger record of the use.
+func EmitItemAction(id string, action *types.Action, viewerId string, item types.Item) {
+	viewer, err := viewers.GetViewerById(viewerId)
+	if err == nil {
+		action.Viewer = viewer
+	}
+	action.Item = &item
+
+	listener.EmitEvent("items", id, types.BreakfastEvent{
+		Type:     types.EventTypeAction,
+		Platform: "items",
+		Data:     action,
+	})
+}
+
+// Finds the oldest of a viewer's usable items or loot boxes with a label, ignoring case. Returns the id of
+// the viewer item.
+func FindOwnedItemByLabel(viewerId string, label string) (string, error) {
+	var query struct {
+		Id string `db:"id"`
+	}
+
+	err := services.App.Dao().DB().
+		Select("vi.id").
+		From("viewer_items as vi").
+		InnerJoin("items as i", dbx.NewExp("vi.item = i.id")).
+		Where(dbx.NewExp(
+			"vi.owner = {:viewerId} AND i.type IN ('CONSUMABLE', 'COLLECTABLE', 'LOOT_BOX') AND i.label = {:label} COLLATE NOCASE",
+			dbx.Params{"viewerId": viewerId, "label": label},
+		)).
+		OrderBy("vi.created ASC", "vi.id ASC").
+		Limit(1).
+		One(&query)
+	if err != nil {
+		return "", err
+	}
+
+	return query.Id, nil
+}
+
+/*
+ViewerItemId - which of the viewer's items to use
+Actor - who used the item, e.g. "viewer:<id>" or "user:<id>" for a streamer using it on a viewer's behalf
+*/
+type UseRequest struct {
+	ViewerId     string
+	ViewerItemId string
+	Actor        string
+}
+
+// Uses one of a viewer's items, running its action. Consumables are taken from the viewer and
+// every use is recorded in the ledger in the same transaction. The action is sent to overlays
+// once the use is saved.
+func Use(request UseRequest) (*types.Action, error) {
+	var action types.Action
+	var item types.Item
+	var useId string
+
+	err := services.App.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		viewerItem, err := txDao.FindFirstRecordByFilter(
+			"viewer_items",
+			"id = {:id} && owner = {:owner}",
+			dbx.Params{"id": request.ViewerItemId, "owner": request.ViewerId},
+		)
+		if err != nil {
+			return err
+		}
+
+		itemRecord, err := txDao.FindRecordById("items", viewerItem.GetString("item"))
+		if err != nil {
+			return err
+		}
+
+		if itemRecord.GetString("type") == "LOOT_BOX" {
+			return ErrUseLootBox
+		}
+
+		if !IsUsable(itemRecord.GetString("type")) {
+			return ErrNotUsable
+		}
+
+		action, err = ActionFromRecord(itemRecord)
+		if err != nil {
+			return err
+		}
+
+		delta := 0
+		if itemRecord.GetString("type") == "CONSUMABLE" {
+			delta = -1
+
+			// Take one from the stack, or the viewer item itself when it's the last one
+			result, err := txDao.DB().
+				NewQuery("UPDATE viewer_items SET quantity = quantity - 1, updated = {:updated} WHERE id = {:id} AND quantity > 1").
+				Bind(dbx.Params{"id": viewerItem.Id, "updated": time.Now()}).
+				Execute()
+			if err != nil {
+				return err
+			}
+
+			if affected, _ := result.RowsAffected(); affected == 0 {
+				result, err := txDao.DB().
+					Delete("viewer_items", dbx.HashExp{"id": viewerItem.Id}).
+					Execute()
+				if err != nil {
+					return err
+				}
+
+				if affected, _ := result.RowsAffected(); affected == 0 {
+					return errors.New("item was already used")
+				}
+			}
+		}
+
+		item = ItemFromRecord(itemRecord)
+
+		useId, err = RecordItemChange(txDao, ItemChange{
+			ViewerId: request.ViewerId,
+			ItemId:   itemRecord.Id,
+			Delta:    delta,
+			Reason:   "Used " + item.Label,
+			Source:   "use:" + viewerItem.Id,
+			Actor:    request.Actor,
+		})
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	EmitItemAction(useId, &action, request.ViewerId, item)
+
+	return &action, nil
+}
+
+// Uses a viewer's item, or opens it when it's a loot box. Returns the item's action, or what
+// dropped from the loot box.
+func Redeem(request UseRequest) (any, error) {
+	var query struct {
+		Type string `db:"type"`
+	}
+
+	err := services.App.Dao().DB().
+		Select("i.type").
+		From("viewer_items as vi").
+		InnerJoin("items as i", dbx.NewExp("vi.item = i.id")).
+		Where(dbx.HashExp{"vi.id": request.ViewerItemId, "vi.owner": request.ViewerId}).
+		One(&query)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Type == "LOOT_BOX" {
+		opened, err := Open(request)
+		if err != nil {
+			return nil, err
+		}
+
+		return opened, nil
+	}
+
+	action, err := Use(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return action, nil
+}
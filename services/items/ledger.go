@@ -0,0 +1,47 @@
+package items
+
+import (
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+/*
+Delta - how many of the item the viewer gained or lost, 0 for uses that don't use the item up
+Reason - why the viewer's items changed, shown in the viewer's history
+Source - the id of what caused the change, e.g. "shop:<purchaseId>"
+Actor - who made the change, e.g. "viewer:<id>" or "user:<id>" for a streamer
+*/
+type ItemChange struct {
+	ViewerId string
+	ItemId   string
+	Delta    int
+	Reason   string
+	Source   string
+	Actor    string
+}
+
+// Records a change to a viewer's items in the ledger, returning the id of the ledger record
+func RecordItemChange(txDao *daos.Dao, change ItemChange) (string, error) {
+	collection, err := txDao.FindCollectionByNameOrId("item_transactions")
+	if err != nil {
+		return "", err
+	}
+
+	record := models.NewRecord(collection)
+	record.RefreshId()
+	record.Set("viewer", change.ViewerId)
+	record.Set("item", change.ItemId)
+	record.Set("delta", change.Delta)
+	record.Set("reason", change.Reason)
+	record.Set("source", change.Source)
+	record.Set("actor", change.Actor)
+
+	{
+		err := txDao.SaveRecord(record)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return record.Id, nil
+}
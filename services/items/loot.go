@@ -0,0 +1,423 @@
+package items
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/listener"
+	"breakfast/services/events/types"
+	"breakfast/services/viewers"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathRand "math/rand/v2"
+	"slices"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// Rarities from most to least common
+var Rarities = []string{"COMMON", "UNCOMMON", "RARE", "EPIC", "LEGENDARY"}
+
+var ErrNotLootBox = errors.New("item isn't a loot box")
+var ErrEmptyLootTable = errors.New("loot box doesn't have anything in its loot table")
+var ErrInvalidRarity = errors.New("rarity must be one of COMMON, UNCOMMON, RARE, EPIC or LEGENDARY")
+var ErrInvalidSeed = errors.New("seed must be 32 hex characters")
+
+/*
+Item - the id of the item that can drop
+Weight - how likely the item is to drop compared to the rest of the table
+Rarity - the rarity tier of the drop, used by pity rules
+Quantity - how many of the item drop at once, 1 when unset
+*/
+type LootEntry struct {
+	Item     string `json:"item"`
+	Weight   int    `json:"weight"`
+	Rarity   string `json:"rarity"`
+	Quantity int    `json:"quantity"`
+}
+
+/*
+Rarity - the rarity the viewer is guaranteed, or anything rarer
+After - how many boxes it takes to be guaranteed the rarity, e.g. 10 means every tenth box
+without it
+*/
+type PityRule struct {
+	Rarity string `json:"rarity"`
+	After  int    `json:"after"`
+}
+
+type LootTable struct {
+	Entries []LootEntry `json:"entries"`
+	Pity    []PityRule  `json:"pity"`
+}
+
+func rarityRank(rarity string) int {
+	return slices.Index(Rarities, rarity)
+}
+
+// Reads the loot table of a loot box
+func LootTableFromRecord(record *models.Record) (LootTable, error) {
+	var table LootTable
+	if value := record.GetString("lootTable"); value == "" || value == "null" {
+		return table, ErrEmptyLootTable
+	}
+
+	err := record.UnmarshalJSONField("lootTable", &table)
+	return table, err
+}
+
+// Checks every entry of a loot table drops an item that exists and that every pity rule can be
+// met by something in the table
+func ValidateLootTable(dao *daos.Dao, table LootTable) error {
+	if len(table.Entries) == 0 {
+		return ErrEmptyLootTable
+	}
+
+	for _, entry := range table.Entries {
+		if entry.Weight <= 0 {
+			return errors.New("loot table weights must be more than zero")
+		}
+
+		if entry.Quantity < 0 {
+			return ErrInvalidQuantity
+		}
+
+		if rarityRank(entry.Rarity) < 0 {
+			return ErrInvalidRarity
+		}
+
+		_, err := dao.FindRecordById("items", entry.Item)
+		if err != nil {
+			return fmt.Errorf("loot table item %q not found", entry.Item)
+		}
+	}
+
+	for _, rule := range table.Pity {
+		rank := rarityRank(rule.Rarity)
+		if rank < 0 {
+			return ErrInvalidRarity
+		}
+
+		if rule.After <= 0 {
+			return errors.New("pity rules need to happen after at least one box")
+		}
+
+		reachable := slices.ContainsFunc(table.Entries, func(entry LootEntry) bool {
+			return rarityRank(entry.Rarity) >= rank
+		})
+		if !reachable {
+			return fmt.Errorf("nothing in the loot table is %s or rarer", rule.Rarity)
+		}
+	}
+
+	return nil
+}
+
+// Makes a new seed for a draw, kept with the drop so the draw can be checked later
+func newLootSeed() (string, error) {
+	seed := make([]byte, 16)
+
+	_, err := rand.Read(seed)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(seed), nil
+}
+
+// Draws an entry from a loot table, only drawing entries of the pity rarity or rarer when it's
+// set. The same seed, table and pity always draw the same entry. Returns the entry and the roll
+// that picked it.
+func DrawLoot(seed string, table LootTable, pity string) (LootEntry, int, error) {
+	bytes, err := hex.DecodeString(seed)
+	if err != nil || len(bytes) != 16 {
+		return LootEntry{}, 0, ErrInvalidSeed
+	}
+
+	minRank := 0
+	if pity != "" {
+		minRank = rarityRank(pity)
+	}
+
+	entries := []LootEntry{}
+	total := 0
+	for _, entry := range table.Entries {
+		if rarityRank(entry.Rarity) < minRank {
+			continue
+		}
+
+		entries = append(entries, entry)
+		total += entry.Weight
+	}
+
+	if total == 0 {
+		return LootEntry{}, 0, ErrEmptyLootTable
+	}
+
+	random := mathRand.New(mathRand.NewPCG(
+		binary.BigEndian.Uint64(bytes[:8]),
+		binary.BigEndian.Uint64(bytes[8:]),
+	))
+	roll := random.IntN(total)
+
+	remaining := roll
+	for _, entry := range entries {
+		if remaining < entry.Weight {
+			return entry, roll, nil
+		}
+		remaining -= entry.Weight
+	}
+
+	// Unreachable, the roll is always less than the total weight
+	return entries[len(entries)-1], roll, nil
+}
+
+// Finds the rarest pity rule the viewer has hit with this box, empty when none have been hit
+func pityRarity(txDao *daos.Dao, viewerId string, lootBoxId string, rules []PityRule) (string, error) {
+	var counts []struct {
+		Rarity string `db:"rarity"`
+		Count  int    `db:"count"`
+	}
+
+	err := txDao.DB().
+		Select("rarity", "count").
+		From("loot_pity").
+		Where(dbx.HashExp{"viewer": viewerId, "lootBox": lootBoxId}).
+		All(&counts)
+	if err != nil {
+		return "", err
+	}
+
+	pity := ""
+	for _, rule := range rules {
+		count := 0
+		for _, row := range counts {
+			if row.Rarity == rule.Rarity {
+				count = row.Count
+			}
+		}
+
+		// This box is the one that reaches the pity
+		if count+1 < rule.After {
+			continue
+		}
+
+		if pity == "" || rarityRank(rule.Rarity) > rarityRank(pity) {
+			pity = rule.Rarity
+		}
+	}
+
+	return pity, nil
+}
+
+// Resets the pity of every rarity the drop met, and counts one more box for the rest
+func updatePity(txDao *daos.Dao, viewerId string, lootBoxId string, rules []PityRule, dropped string) error {
+	for _, rule := range rules {
+		query := `INSERT INTO loot_pity (viewer, lootBox, rarity, count) VALUES ({:viewer}, {:lootBox}, {:rarity}, 1)
+			ON CONFLICT (viewer, lootBox, rarity) DO UPDATE SET count = count + 1`
+		if rarityRank(dropped) >= rarityRank(rule.Rarity) {
+			query = `INSERT INTO loot_pity (viewer, lootBox, rarity, count) VALUES ({:viewer}, {:lootBox}, {:rarity}, 0)
+				ON CONFLICT (viewer, lootBox, rarity) DO UPDATE SET count = 0`
+		}
+
+		_, err := txDao.DB().
+			NewQuery(query).
+			Bind(dbx.Params{"viewer": viewerId, "lootBox": lootBoxId, "rarity": rule.Rarity}).
+			Execute()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+LootBoxId - the id of the loot box item, not the viewer's item
+Source - the id of what opened the box, e.g. "shop:<purchaseId>"
+Actor - who opened the box, e.g. "viewer:<id>" or "user:<id>" for a streamer
+*/
+type OpenRequest struct {
+	ViewerId  string
+	LootBoxId string
+	Source    string
+	Actor     string
+}
+
+// Opens one of a viewer's loot boxes inside a transaction, taking the box and giving the viewer
+// what it drops. The seed, roll and loot table are saved in a loot drop record so the draw can be
+// replayed with DrawLoot.
+func OpenTx(txDao *daos.Dao, request OpenRequest) (*types.LootBoxOpened, error) {
+	lootBox, err := txDao.FindRecordById("items", request.LootBoxId)
+	if err != nil {
+		return nil, err
+	}
+
+	if lootBox.GetString("type") != "LOOT_BOX" {
+		return nil, ErrNotLootBox
+	}
+
+	table, err := LootTableFromRecord(lootBox)
+	if err != nil {
+		return nil, err
+	}
+
+	{
+		_, err := RevokeTx(txDao, GrantRequest{
+			ViewerId: request.ViewerId,
+			ItemId:   lootBox.Id,
+			Quantity: 1,
+			Reason:   "Opened " + lootBox.GetString("label"),
+			Source:   request.Source,
+			Actor:    request.Actor,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pity, err := pityRarity(txDao, request.ViewerId, lootBox.Id, table.Pity)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := newLootSeed()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, roll, err := DrawLoot(seed, table, pity)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Quantity == 0 {
+		entry.Quantity = 1
+	}
+
+	{
+		err := updatePity(txDao, request.ViewerId, lootBox.Id, table.Pity, entry.Rarity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	collection, err := txDao.FindCollectionByNameOrId("loot_drops")
+	if err != nil {
+		return nil, err
+	}
+
+	drop := models.NewRecord(collection)
+	drop.RefreshId()
+	drop.Set("viewer", request.ViewerId)
+	drop.Set("lootBox", lootBox.Id)
+	drop.Set("item", entry.Item)
+	drop.Set("quantity", entry.Quantity)
+	drop.Set("rarity", entry.Rarity)
+	drop.Set("pity", pity)
+	drop.Set("seed", seed)
+	drop.Set("roll", roll)
+	drop.Set("lootTable", table)
+	drop.Set("source", request.Source)
+	drop.Set("actor", request.Actor)
+
+	{
+		err := txDao.SaveRecord(drop)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	{
+		_, _, err := GrantTx(txDao, GrantRequest{
+			ViewerId: request.ViewerId,
+			ItemId:   entry.Item,
+			Quantity: entry.Quantity,
+			Reason:   "Found in " + lootBox.GetString("label"),
+			Source:   "loot:" + drop.Id,
+			Actor:    request.Actor,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	itemRecord, err := txDao.FindRecordById("items", entry.Item)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.LootBoxOpened{
+		Id:       drop.Id,
+		LootBox:  ItemFromRecord(lootBox),
+		Item:     ItemFromRecord(itemRecord),
+		Quantity: entry.Quantity,
+		Rarity:   entry.Rarity,
+		Pity:     pity,
+		Seed:     seed,
+	}, nil
+}
+
+// Sends what dropped from a loot box to overlays, along with the dropped item's action for items
+// that can't be used
+func EmitLootBoxOpened(opened *types.LootBoxOpened, viewerId string) {
+	viewer, err := viewers.GetViewerById(viewerId)
+	if err == nil {
+		opened.Viewer = viewer
+	}
+
+	listener.EmitEvent("items", opened.Id, types.BreakfastEvent{
+		Type:     types.EventTypeLootBoxOpened,
+		Platform: "items",
+		Data:     opened,
+	})
+
+	if IsUsable(opened.Item.Type) {
+		return
+	}
+
+	itemRecord, err := services.App.Dao().FindRecordById("items", opened.Item.Id)
+	if err != nil {
+		return
+	}
+
+	action, err := ActionFromRecord(itemRecord)
+	if err == nil {
+		EmitItemAction(opened.Id, &action, viewerId, opened.Item)
+	}
+}
+
+// Opens one of a viewer's loot boxes and emits a loot-box-opened event with what dropped
+func Open(request UseRequest) (*types.LootBoxOpened, error) {
+	var opened *types.LootBoxOpened
+
+	err := services.App.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		viewerItem, err := txDao.FindFirstRecordByFilter(
+			"viewer_items",
+			"id = {:id} && owner = {:owner}",
+			dbx.Params{"id": request.ViewerItemId, "owner": request.ViewerId},
+		)
+		if err != nil {
+			return err
+		}
+
+		opened, err = OpenTx(txDao, OpenRequest{
+			ViewerId:  request.ViewerId,
+			LootBoxId: viewerItem.GetString("item"),
+			Source:    "use:" + viewerItem.Id,
+			Actor:     request.Actor,
+		})
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	EmitLootBoxOpened(opened, request.ViewerId)
+
+	return opened, nil
+}
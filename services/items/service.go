@@ -0,0 +1,290 @@
+package items
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/listener"
+	"breakfast/services/viewers"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+func RegisterService(app *pocketbase.PocketBase) {
+	listener.OnEvent(handleUseCommand)
+
+	// Viewer items made through the collection api are one item unless said otherwise
+	app.OnRecordBeforeCreateRequest("viewer_items").Add(func(e *core.RecordCreateEvent) error {
+		if e.Record.GetInt("quantity") < 1 {
+			e.Record.Set("quantity", 1)
+		}
+
+		return nil
+	})
+
+	validate := func(record *models.Record) error {
+		if record.GetString("type") != "LOOT_BOX" {
+			return nil
+		}
+
+		table, err := LootTableFromRecord(record)
+		if err != nil {
+			return err
+		}
+
+		return ValidateLootTable(app.Dao(), table)
+	}
+
+	app.OnRecordBeforeCreateRequest("items").Add(func(e *core.RecordCreateEvent) error {
+		return validate(e.Record)
+	})
+
+	app.OnRecordBeforeUpdateRequest("items").Add(func(e *core.RecordUpdateEvent) error {
+		return validate(e.Record)
+	})
+
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/api/breakfast/viewers/:id/items/:viewerItemId/use", func(c echo.Context) error {
+			viewerId := c.PathParam("id")
+
+			// Validate user is authenticated, viewers can use their own items
+			permission := viewers.GetPermission(c, viewerId)
+			if permission == viewers.PermissionNone {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			actor := "viewer:" + viewerId
+			if permission == viewers.PermissionUser {
+				actor = "user:" + apis.RequestInfo(c).AuthRecord.Id
+			}
+
+			result, err := Redeem(UseRequest{
+				ViewerId:     viewerId,
+				ViewerItemId: c.PathParam("viewerItemId"),
+				Actor:        actor,
+			})
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]string{"message": "Viewer doesn't own the item"})
+			}
+			if err != nil {
+				return c.JSON(400, map[string]string{"message": "Failed to use the item", "error": err.Error()})
+			}
+
+			return c.JSON(200, result)
+		})
+
+		e.Router.POST("/api/breakfast/viewers/:id/items/grant", func(c echo.Context) error {
+			return handleGrant(c, true)
+		})
+
+		e.Router.POST("/api/breakfast/viewers/:id/items/revoke", func(c echo.Context) error {
+			return handleGrant(c, false)
+		})
+
+		e.Router.POST("/api/breakfast/trades", func(c echo.Context) error {
+			info := apis.RequestInfo(c)
+			auth := info.AuthRecord
+
+			if auth == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			var body struct {
+				Sender    string      `json:"sender"`
+				Recipient string      `json:"recipient"`
+				Offered   []TradeItem `json:"offered"`
+				Requested []TradeItem `json:"requested"`
+			}
+
+			{
+				err := c.Bind(&body)
+				if err != nil {
+					return c.JSON(400, map[string]string{"message": "Failed to parse trade"})
+				}
+			}
+
+			offer := TradeOffer{
+				RecipientId: body.Recipient,
+				Offered:     body.Offered,
+				Requested:   body.Requested,
+			}
+
+			// Viewers trade their own items, streamers can offer on a viewer's behalf
+			switch auth.Collection().Id {
+			case "viewers":
+				offer.SenderId = auth.Id
+				offer.Actor = "viewer:" + auth.Id
+			case "users":
+				if body.Sender == "" {
+					return c.JSON(400, map[string]string{"message": "A viewer is needed to trade on behalf of"})
+				}
+
+				offer.SenderId = body.Sender
+				offer.Actor = "user:" + auth.Id
+			default:
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			trade, err := OfferTrade(offer)
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]string{"message": "Item or viewer not found"})
+			}
+			if err != nil {
+				return c.JSON(400, map[string]string{"message": "Failed to offer the trade", "error": err.Error()})
+			}
+
+			return c.JSON(200, trade)
+		})
+
+		e.Router.POST("/api/breakfast/trades/:id/accept", func(c echo.Context) error {
+			trade, actor, err := authorizeTrade(c, "recipient")
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]string{"message": "Trade not found"})
+			}
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			traded, err := AcceptTrade(trade.Id, actor)
+			if errors.Is(err, ErrNotEnoughItems) {
+				return c.JSON(400, map[string]string{"message": "One of the viewers no longer has the items", "error": err.Error()})
+			}
+			if err != nil {
+				return c.JSON(400, map[string]string{"message": "Failed to accept the trade", "error": err.Error()})
+			}
+
+			return c.JSON(200, traded)
+		})
+
+		e.Router.POST("/api/breakfast/trades/:id/decline", func(c echo.Context) error {
+			trade, _, err := authorizeTrade(c, "recipient")
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]string{"message": "Trade not found"})
+			}
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			{
+				err := CloseTrade(trade.Id, TradeDeclined)
+				if err != nil {
+					return c.JSON(400, map[string]string{"message": "Failed to decline the trade", "error": err.Error()})
+				}
+			}
+
+			return c.JSON(200, map[string]string{"message": "OK"})
+		})
+
+		e.Router.POST("/api/breakfast/trades/:id/cancel", func(c echo.Context) error {
+			trade, _, err := authorizeTrade(c, "sender")
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]string{"message": "Trade not found"})
+			}
+			if err != nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			{
+				err := CloseTrade(trade.Id, TradeCancelled)
+				if err != nil {
+					return c.JSON(400, map[string]string{"message": "Failed to cancel the trade", "error": err.Error()})
+				}
+			}
+
+			return c.JSON(200, map[string]string{"message": "OK"})
+		})
+
+		return nil
+	})
+}
+
+// Gives or takes items from a viewer, only streamers can do this
+func handleGrant(c echo.Context, grant bool) error {
+	viewerId := c.PathParam("id")
+
+	// Validate user is authenticated
+	if !viewers.HasPermission(c, viewerId, viewers.PermissionUser) {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+	}
+
+	var body struct {
+		Item     string `json:"item"`
+		Quantity int    `json:"quantity"`
+		Reason   string `json:"reason"`
+	}
+
+	{
+		err := c.Bind(&body)
+		if err != nil {
+			return c.JSON(400, map[string]string{"message": "Failed to parse items"})
+		}
+	}
+
+	if body.Quantity == 0 {
+		body.Quantity = 1
+	}
+
+	request := GrantRequest{
+		ViewerId: viewerId,
+		ItemId:   body.Item,
+		Quantity: body.Quantity,
+		Reason:   body.Reason,
+		Actor:    "user:" + apis.RequestInfo(c).AuthRecord.Id,
+	}
+
+	if !grant {
+		if request.Reason == "" {
+			request.Reason = "Taken by streamer"
+		}
+
+		err := Revoke(request)
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(404, map[string]string{"message": "Item not found"})
+		}
+		if err != nil {
+			return c.JSON(400, map[string]string{"message": "Failed to take the items", "error": err.Error()})
+		}
+
+		return c.JSON(200, map[string]string{"message": "OK"})
+	}
+
+	if request.Reason == "" {
+		request.Reason = "Given by streamer"
+	}
+
+	granted, err := Grant(request)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(404, map[string]string{"message": "Item not found"})
+	}
+	if err != nil {
+		return c.JSON(400, map[string]string{"message": "Failed to give the items", "error": err.Error()})
+	}
+
+	return c.JSON(200, granted)
+}
+
+var errTradeUnauthorized = errors.New("not part of the trade")
+
+// Finds the trade in the request and checks the signed in record can act for one side of it,
+// returning the actor to record
+func authorizeTrade(c echo.Context, side string) (*models.Record, string, error) {
+	trade, err := services.App.Dao().FindRecordById("item_trades", c.PathParam("id"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	viewerId := trade.GetString(side)
+	switch viewers.GetPermission(c, viewerId) {
+	case viewers.PermissionViewer:
+		return trade, "viewer:" + viewerId, nil
+	case viewers.PermissionUser:
+		return trade, "user:" + apis.RequestInfo(c).AuthRecord.Id, nil
+	}
+
+	return nil, "", errTradeUnauthorized
+}
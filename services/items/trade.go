@@ -0,0 +1,318 @@
+package items
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/listener"
+	"breakfast/services/events/types"
+	"breakfast/services/viewers"
+	"errors"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+const TradePending = "PENDING"
+const TradeAccepted = "ACCEPTED"
+const TradeDeclined = "DECLINED"
+const TradeCancelled = "CANCELLED"
+
+var ErrTradeSelf = errors.New("viewers can't trade with themselves")
+var ErrTradeEmpty = errors.New("trade doesn't have any items")
+var ErrTradeNotPending = errors.New("trade isn't pending anymore")
+var ErrNotTradable = errors.New("private items can't be traded")
+
+type TradeItem struct {
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+}
+
+/*
+Offered - what the sender gives the recipient
+Requested - what the sender wants from the recipient, empty for gifts
+Actor - who made the offer, e.g. "viewer:<id>" or "user:<id>" for a streamer
+*/
+type TradeOffer struct {
+	SenderId    string
+	RecipientId string
+	Offered     []TradeItem
+	Requested   []TradeItem
+	Actor       string
+}
+
+// Adds up duplicate items so ownership is checked against the total
+func mergeTradeItems(tradeItems []TradeItem) ([]TradeItem, error) {
+	merged := []TradeItem{}
+	indexes := map[string]int{}
+
+	for _, tradeItem := range tradeItems {
+		if tradeItem.Quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+
+		if i, exists := indexes[tradeItem.Item]; exists {
+			merged[i].Quantity += tradeItem.Quantity
+			continue
+		}
+
+		indexes[tradeItem.Item] = len(merged)
+		merged = append(merged, tradeItem)
+	}
+
+	return merged, nil
+}
+
+// Checks a viewer owns enough of every item and that none of them are private
+func validateTradeItems(txDao *daos.Dao, viewerId string, tradeItems []TradeItem) ([]types.TradedItem, error) {
+	traded := []types.TradedItem{}
+
+	for _, tradeItem := range tradeItems {
+		itemRecord, err := txDao.FindRecordById("items", tradeItem.Item)
+		if err != nil {
+			return nil, err
+		}
+
+		if itemRecord.GetString("visibility") == "PRIVATE" {
+			return nil, ErrNotTradable
+		}
+
+		var owned struct {
+			Quantity int `db:"quantity"`
+		}
+
+		{
+			err := txDao.DB().
+				Select("COALESCE(SUM(quantity), 0) as quantity").
+				From("viewer_items").
+				Where(dbx.HashExp{"owner": viewerId, "item": itemRecord.Id}).
+				One(&owned)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if owned.Quantity < tradeItem.Quantity {
+			return nil, ErrNotEnoughItems
+		}
+
+		traded = append(traded, types.TradedItem{
+			Item:     ItemFromRecord(itemRecord),
+			Quantity: tradeItem.Quantity,
+		})
+	}
+
+	return traded, nil
+}
+
+// Offers a trade to a viewer, or a gift when nothing is requested. Nothing changes hands until
+// the recipient accepts, so ownership is checked again then.
+func OfferTrade(offer TradeOffer) (*models.Record, error) {
+	if offer.SenderId == offer.RecipientId {
+		return nil, ErrTradeSelf
+	}
+
+	offered, err := mergeTradeItems(offer.Offered)
+	if err != nil {
+		return nil, err
+	}
+
+	requested, err := mergeTradeItems(offer.Requested)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(offered) == 0 && len(requested) == 0 {
+		return nil, ErrTradeEmpty
+	}
+
+	var trade *models.Record
+	err = services.App.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		{
+			_, err := txDao.FindRecordById("viewers", offer.RecipientId)
+			if err != nil {
+				return err
+			}
+		}
+
+		{
+			_, err := validateTradeItems(txDao, offer.SenderId, offered)
+			if err != nil {
+				return err
+			}
+		}
+
+		{
+			_, err := validateTradeItems(txDao, offer.RecipientId, requested)
+			if err != nil {
+				return err
+			}
+		}
+
+		collection, err := txDao.FindCollectionByNameOrId("item_trades")
+		if err != nil {
+			return err
+		}
+
+		trade = models.NewRecord(collection)
+		trade.RefreshId()
+		trade.Set("sender", offer.SenderId)
+		trade.Set("recipient", offer.RecipientId)
+		trade.Set("offered", offered)
+		trade.Set("requested", requested)
+		trade.Set("status", TradePending)
+		trade.Set("actor", offer.Actor)
+
+		return txDao.SaveRecord(trade)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return trade, nil
+}
+
+// Moves items from one viewer to another for a trade
+func transferTradeItems(txDao *daos.Dao, tradeId string, fromId string, toId string, tradeItems []types.TradedItem, actor string) error {
+	for _, tradeItem := range tradeItems {
+		request := GrantRequest{
+			ViewerId: fromId,
+			ItemId:   tradeItem.Item.Id,
+			Quantity: tradeItem.Quantity,
+			Reason:   "Traded away " + tradeItem.Item.Label,
+			Source:   "trade:" + tradeId,
+			Actor:    actor,
+		}
+
+		{
+			_, err := RevokeTx(txDao, request)
+			if err != nil {
+				return err
+			}
+		}
+
+		request.ViewerId = toId
+		request.Reason = "Traded for " + tradeItem.Item.Label
+
+		{
+			_, _, err := GrantTx(txDao, request)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Accepts a pending trade, swapping the items between both viewers in one transaction. Fails
+// without moving anything when either viewer no longer has what they're giving. Emits an
+// item-traded event once the items have moved.
+func AcceptTrade(tradeId string, actor string) (*types.ItemTraded, error) {
+	traded := types.ItemTraded{Id: tradeId}
+
+	var senderId, recipientId string
+	err := services.App.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		trade, err := txDao.FindRecordById("item_trades", tradeId)
+		if err != nil {
+			return err
+		}
+
+		senderId = trade.GetString("sender")
+		recipientId = trade.GetString("recipient")
+
+		// Only one accept can move a trade out of pending
+		result, err := txDao.DB().
+			Update(
+				"item_trades",
+				dbx.Params{"status": TradeAccepted, "updated": time.Now()},
+				dbx.HashExp{"id": tradeId, "status": TradePending},
+			).
+			Execute()
+		if err != nil {
+			return err
+		}
+
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			return ErrTradeNotPending
+		}
+
+		var offered, requested []TradeItem
+		{
+			err := trade.UnmarshalJSONField("offered", &offered)
+			if err != nil {
+				return err
+			}
+		}
+		{
+			err := trade.UnmarshalJSONField("requested", &requested)
+			if err != nil {
+				return err
+			}
+		}
+
+		traded.Offered, err = validateTradeItems(txDao, senderId, offered)
+		if err != nil {
+			return err
+		}
+
+		traded.Requested, err = validateTradeItems(txDao, recipientId, requested)
+		if err != nil {
+			return err
+		}
+
+		{
+			err := transferTradeItems(txDao, tradeId, senderId, recipientId, traded.Offered, actor)
+			if err != nil {
+				return err
+			}
+		}
+
+		return transferTradeItems(txDao, tradeId, recipientId, senderId, traded.Requested, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := viewers.GetViewerById(senderId)
+	if err == nil {
+		traded.Sender = sender
+	}
+
+	recipient, err := viewers.GetViewerById(recipientId)
+	if err == nil {
+		traded.Recipient = recipient
+	}
+
+	listener.EmitEvent("items", traded.Id, types.BreakfastEvent{
+		Type:     types.EventTypeItemTraded,
+		Platform: "items",
+		Data:     &traded,
+	})
+
+	return &traded, nil
+}
+
+// Declines or cancels a pending trade
+func CloseTrade(tradeId string, status string) error {
+	if status != TradeDeclined && status != TradeCancelled {
+		return errors.New("trades can only be declined or cancelled")
+	}
+
+	result, err := services.App.Dao().DB().
+		Update(
+			"item_trades",
+			dbx.Params{"status": status, "updated": time.Now()},
+			dbx.HashExp{"id": tradeId, "status": TradePending},
+		).
+		Execute()
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrTradeNotPending
+	}
+
+	return nil
+}
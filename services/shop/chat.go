@@ -0,0 +1,53 @@
+package shop
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/types"
+	"strings"
+)
+
+const BuyCommand = "!buy"
+
+// Buys an item for a chatter that sends "!buy <item label>"
+func handleBuyCommand(provider string, providerId string, event types.BreakfastEvent) {
+	message, ok := event.Data.(*types.ChatMessage)
+	if !ok || message.Viewer == nil || message.Source != nil {
+		return
+	}
+
+	command, label, found := strings.Cut(strings.TrimSpace(message.Text), " ")
+	if !found || !strings.EqualFold(command, BuyCommand) {
+		return
+	}
+
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return
+	}
+
+	item, err := FindItemByLabel(label)
+	if err != nil {
+		services.App.Logger().Debug(
+			"SHOP Chatter tried to buy an item that isn't for sale",
+			"viewer", message.Viewer.Id,
+			"label", label,
+		)
+		return
+	}
+
+	channel := message.Channel
+	_, err = Purchase(PurchaseRequest{
+		ViewerId: message.Viewer.Id,
+		ItemId:   item.Id,
+		Actor:    "viewer:" + message.Viewer.Id,
+		Channel:  &channel,
+	})
+	if err != nil {
+		services.App.Logger().Info(
+			"SHOP Chat purchase failed",
+			"viewer", message.Viewer.Id,
+			"item", item.Id,
+			"error", err.Error(),
+		)
+	}
+}
@@ -0,0 +1,105 @@
+package shop
+
+import (
+	"breakfast/services/events/listener"
+	"breakfast/services/viewers"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+func RegisterService(app *pocketbase.PocketBase) {
+	listener.OnEvent(handleBuyCommand)
+
+	// Shop info is parsed on every purchase, so bad prices are caught when the item is saved
+	validate := func(record *models.Record) error {
+		_, err := parseShopInfo(record)
+		return err
+	}
+
+	app.OnRecordBeforeCreateRequest("items").Add(func(e *core.RecordCreateEvent) error {
+		return validate(e.Record)
+	})
+
+	app.OnRecordBeforeUpdateRequest("items").Add(func(e *core.RecordUpdateEvent) error {
+		return validate(e.Record)
+	})
+
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		// Public so viewers can browse the shop without logging in
+		e.Router.GET("/api/breakfast/shop", func(c echo.Context) error {
+			items, err := ListItems()
+			if err != nil {
+				return c.JSON(500, map[string]string{"message": "Failed to list shop items", "error": err.Error()})
+			}
+
+			return c.JSON(200, map[string]any{
+				"items": items,
+			})
+		})
+
+		e.Router.POST("/api/breakfast/shop/purchase", func(c echo.Context) error {
+			info := apis.RequestInfo(c)
+			auth := info.AuthRecord
+
+			if auth == nil {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			var body struct {
+				Item     string `json:"item"`
+				Currency string `json:"currency"`
+				Viewer   string `json:"viewer"`
+			}
+
+			{
+				err := c.Bind(&body)
+				if err != nil {
+					return c.JSON(400, map[string]string{"message": "Failed to parse purchase"})
+				}
+			}
+
+			request := PurchaseRequest{
+				ItemId:   body.Item,
+				Currency: body.Currency,
+			}
+
+			// Viewers buy for themselves, streamers can buy on a viewer's behalf
+			switch auth.Collection().Id {
+			case "viewers":
+				request.ViewerId = auth.Id
+				request.Actor = "viewer:" + auth.Id
+			case "users":
+				if body.Viewer == "" {
+					return c.JSON(400, map[string]string{"message": "A viewer is needed to buy on behalf of"})
+				}
+
+				request.ViewerId = body.Viewer
+				request.Actor = "user:" + auth.Id
+			default:
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			purchased, err := Purchase(request)
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]string{"message": "Item or viewer not found"})
+			}
+			if errors.Is(err, viewers.ErrInsufficientFunds) {
+				return c.JSON(400, map[string]string{"message": "Not enough to buy the item", "error": err.Error()})
+			}
+			if err != nil {
+				return c.JSON(400, map[string]string{"message": "Failed to buy the item", "error": err.Error()})
+			}
+
+			return c.JSON(200, purchased)
+		})
+
+		return nil
+	})
+}
@@ -0,0 +1,363 @@
+package shop
+
+import (
+	"breakfast/services"
+	"breakfast/services/events/listener"
+	"breakfast/services/events/types"
+	"breakfast/services/items"
+	"breakfast/services/viewers"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	pbTypes "github.com/pocketbase/pocketbase/tools/types"
+)
+
+var ErrNotForSale = errors.New("item isn't for sale")
+var ErrNotAvailable = errors.New("item isn't available to buy right now")
+var ErrOutOfStock = errors.New("item is out of stock")
+var ErrPurchaseLimit = errors.New("viewer has bought this item as many times as they can")
+var ErrCurrencyRequired = errors.New("item has multiple prices, a currency must be picked")
+var ErrNoPrice = errors.New("item can't be bought with the currency")
+var ErrNegativePrice = errors.New("prices can't be less than zero")
+
+/*
+Prices - the cost in each currency the item can be bought with, empty when the item is free
+Stock - how many are left to sell, nil for unlimited
+PerViewer - how many times each viewer can buy the item, 0 for unlimited
+AvailableFrom - when the item goes on sale, empty for always
+AvailableUntil - when the item stops being sold, empty for always
+*/
+type ShopInfo struct {
+	Prices         map[string]int `json:"prices"`
+	Stock          *int           `json:"stock"`
+	PerViewer      int            `json:"perViewer"`
+	AvailableFrom  string         `json:"availableFrom"`
+	AvailableUntil string         `json:"availableUntil"`
+}
+
+type ShopItem struct {
+	types.Item
+	ShopInfo
+}
+
+func parseShopInfo(record *models.Record) (ShopInfo, error) {
+	var raw struct {
+		Prices         json.RawMessage `json:"prices"`
+		Stock          *int            `json:"stock"`
+		PerViewer      int             `json:"perViewer"`
+		AvailableFrom  string          `json:"availableFrom"`
+		AvailableUntil string          `json:"availableUntil"`
+	}
+
+	info := ShopInfo{Prices: map[string]int{}}
+	if value := record.GetString("shopInfo"); value == "" || value == "null" {
+		return info, nil
+	}
+
+	{
+		err := record.UnmarshalJSONField("shopInfo", &raw)
+		if err != nil {
+			return info, err
+		}
+	}
+
+	// Free items have their prices set to "free"
+	var free string
+	if len(raw.Prices) > 0 && json.Unmarshal(raw.Prices, &free) != nil {
+		err := json.Unmarshal(raw.Prices, &info.Prices)
+		if err != nil {
+			return info, err
+		}
+	}
+
+	for _, price := range info.Prices {
+		if price < 0 {
+			return info, ErrNegativePrice
+		}
+	}
+
+	info.Stock = raw.Stock
+	info.PerViewer = raw.PerViewer
+	info.AvailableFrom = raw.AvailableFrom
+	info.AvailableUntil = raw.AvailableUntil
+
+	return info, nil
+}
+
+// Checks if the item can be bought at a time, ignoring stock
+func (s ShopInfo) AvailableAt(at time.Time) bool {
+	if s.AvailableFrom != "" {
+		from, err := pbTypes.ParseDateTime(s.AvailableFrom)
+		if err == nil && at.Before(from.Time()) {
+			return false
+		}
+	}
+
+	if s.AvailableUntil != "" {
+		until, err := pbTypes.ParseDateTime(s.AvailableUntil)
+		if err == nil && !at.Before(until.Time()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Lists the public items that can be bought right now, sorted by label
+func ListItems() ([]ShopItem, error) {
+	records, err := services.App.Dao().FindRecordsByFilter(
+		"items",
+		"shopPurchasable = true && visibility = 'PUBLIC'",
+		"label",
+		0,
+		0,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	shopItems := []ShopItem{}
+	for _, record := range records {
+		info, err := parseShopInfo(record)
+		if err != nil {
+			services.App.Logger().Error(
+				"SHOP Failed to read item shop info",
+				"item", record.Id,
+				"error", err.Error(),
+			)
+			continue
+		}
+
+		if !info.AvailableAt(now) {
+			continue
+		}
+
+		shopItems = append(shopItems, ShopItem{Item: items.ItemFromRecord(record), ShopInfo: info})
+	}
+
+	return shopItems, nil
+}
+
+// Finds a public item that can be bought by its label, ignoring case
+func FindItemByLabel(label string) (*models.Record, error) {
+	var query struct {
+		Id string `db:"id"`
+	}
+
+	err := services.App.Dao().DB().
+		Select("id").
+		From("items").
+		Where(dbx.NewExp(
+			"shopPurchasable = TRUE AND visibility = 'PUBLIC' AND label = {:label} COLLATE NOCASE",
+			dbx.Params{"label": label},
+		)).
+		One(&query)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.App.Dao().FindRecordById("items", query.Id)
+}
+
+/*
+Currency - which price to pay, can be empty when the item has at most one price
+Actor - who made the purchase, e.g. "viewer:<id>" or "user:<id>" for a streamer buying on a viewer's behalf
+Channel - the channel the purchase was made from through chat
+*/
+type PurchaseRequest struct {
+	ViewerId string
+	ItemId   string
+	Currency string
+	Actor    string
+	Channel  *types.Channel
+}
+
+// Buys an item for a viewer. The stock, purchase record, wallet debit and viewer item are
+// written in one transaction so a failed check leaves nothing behind. Emits an item-purchased
+// event when the purchase goes through, and the item's action for items that can't be used.
+func Purchase(request PurchaseRequest) (*types.ItemPurchased, error) {
+	purchased := types.ItemPurchased{Channel: request.Channel}
+	var grantAction *types.Action
+	var opened *types.LootBoxOpened
+
+	err := services.App.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		itemRecord, err := txDao.FindRecordById("items", request.ItemId)
+		if err != nil {
+			return err
+		}
+
+		if !itemRecord.GetBool("shopPurchasable") || itemRecord.GetString("visibility") == "PRIVATE" {
+			return ErrNotForSale
+		}
+
+		info, err := parseShopInfo(itemRecord)
+		if err != nil {
+			return err
+		}
+
+		if !info.AvailableAt(time.Now()) {
+			return ErrNotAvailable
+		}
+
+		currency, price := request.Currency, 0
+		if len(info.Prices) > 0 {
+			if currency == "" {
+				if len(info.Prices) > 1 {
+					return ErrCurrencyRequired
+				}
+
+				for only := range info.Prices {
+					currency = only
+				}
+			}
+
+			cost, exists := info.Prices[currency]
+			if !exists {
+				return ErrNoPrice
+			}
+			price = cost
+		} else {
+			currency = ""
+		}
+
+		// Take from the stock only while there's some left
+		if info.Stock != nil {
+			result, err := txDao.DB().
+				NewQuery(`UPDATE items SET shopInfo = json_set(shopInfo, '$.stock', json_extract(shopInfo, '$.stock') - 1)
+					WHERE id = {:id} AND json_extract(shopInfo, '$.stock') > 0`).
+				Bind(dbx.Params{"id": itemRecord.Id}).
+				Execute()
+			if err != nil {
+				return err
+			}
+
+			if affected, _ := result.RowsAffected(); affected == 0 {
+				return ErrOutOfStock
+			}
+		}
+
+		collection, err := txDao.FindCollectionByNameOrId("shop_purchases")
+		if err != nil {
+			return err
+		}
+
+		purchase := models.NewRecord(collection)
+		purchase.RefreshId()
+		purchase.Set("viewer", request.ViewerId)
+		purchase.Set("item", itemRecord.Id)
+		purchase.Set("currency", currency)
+		purchase.Set("price", price)
+		purchase.Set("actor", request.Actor)
+
+		{
+			err := txDao.SaveRecord(purchase)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Counted after saving so concurrent purchases can't both slip under the limit
+		if info.PerViewer > 0 {
+			var count struct {
+				Count int `db:"count"`
+			}
+
+			err := txDao.DB().
+				Select("COUNT(*) as count").
+				From("shop_purchases").
+				Where(dbx.HashExp{"viewer": request.ViewerId, "item": itemRecord.Id}).
+				One(&count)
+			if err != nil {
+				return err
+			}
+
+			if count.Count > info.PerViewer {
+				return ErrPurchaseLimit
+			}
+		}
+
+		if price > 0 {
+			_, err := viewers.ApplyWalletChange(txDao, request.ViewerId, viewers.WalletChange{
+				Currency: currency,
+				Amount:   -price,
+				Reason:   "Bought " + itemRecord.GetString("label"),
+				Source:   "shop:" + purchase.Id,
+				Actor:    request.Actor,
+			}, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		{
+			_, _, err := items.GrantTx(txDao, items.GrantRequest{
+				ViewerId: request.ViewerId,
+				ItemId:   itemRecord.Id,
+				Quantity: 1,
+				Reason:   "Bought " + itemRecord.GetString("label"),
+				Source:   "shop:" + purchase.Id,
+				Actor:    request.Actor,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// Loot boxes bought from the shop are opened straight away
+		if itemRecord.GetString("type") == "LOOT_BOX" {
+			var err error
+			opened, err = items.OpenTx(txDao, items.OpenRequest{
+				ViewerId:  request.ViewerId,
+				LootBoxId: itemRecord.Id,
+				Source:    "shop:" + purchase.Id,
+				Actor:     request.Actor,
+			})
+			if err != nil {
+				return err
+			}
+		} else if !items.IsUsable(itemRecord.GetString("type")) {
+			// Items that can't be used run their action when they're given instead
+			action, err := items.ActionFromRecord(itemRecord)
+			if err == nil {
+				grantAction = &action
+			}
+		}
+
+		purchased.Id = purchase.Id
+		purchased.Item = items.ItemFromRecord(itemRecord)
+		purchased.Currency = currency
+		purchased.Price = price
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Include the wallet after the purchase
+	viewer, err := viewers.GetViewerById(request.ViewerId)
+	if err == nil {
+		purchased.Viewer = viewer
+	}
+
+	listener.EmitEvent("shop", purchased.Id, types.BreakfastEvent{
+		Type:     types.EventTypeItemPurchased,
+		Platform: "shop",
+		Data:     &purchased,
+	})
+
+	if grantAction != nil {
+		items.EmitItemAction(purchased.Id, grantAction, request.ViewerId, purchased.Item)
+	}
+
+	if opened != nil {
+		items.EmitLootBoxOpened(opened, request.ViewerId)
+	}
+
+	return &purchased, nil
+}
@@ -0,0 +1,117 @@
+package viewers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+/*
+PermissionNone - not signed in, or signed in as a different viewer
+PermissionViewer - signed in as the viewer the request is about
+PermissionUser - signed in as a streamer, who can manage every viewer
+*/
+type Permission int
+
+const (
+	PermissionNone Permission = iota
+	PermissionViewer
+	PermissionUser
+)
+
+// Works out what the signed in record can do with a viewer
+func GetPermission(c echo.Context, viewerId string) Permission {
+	auth := apis.RequestInfo(c).AuthRecord
+	if auth == nil {
+		return PermissionNone
+	}
+
+	switch auth.Collection().Id {
+	case "users":
+		return PermissionUser
+	case "viewers":
+		if viewerId != "" && auth.Id == viewerId {
+			return PermissionViewer
+		}
+	}
+
+	return PermissionNone
+}
+
+// Checks the signed in record has at least a permission for a viewer
+func HasPermission(c echo.Context, viewerId string, permission Permission) bool {
+	return GetPermission(c, viewerId) >= permission
+}
+
+func registerViewerAuth(app *pocketbase.PocketBase) {
+	// Viewers signing in for the first time get a viewer record made the same way as viewers seen
+	// in chat. Viewers already seen in chat have an external auth, so pocketbase signs them in to
+	// that record instead of getting here.
+	app.OnRecordBeforeAuthWithOAuth2Request("viewers").PreAdd(func(e *core.RecordAuthWithOAuth2Event) error {
+		if e.Record != nil {
+			_, err := app.Dao().FindFirstExternalAuthByExpr(dbx.HashExp{
+				"collectionId": e.Record.Collection().Id,
+				"provider":     e.ProviderName,
+				"providerId":   e.OAuth2User.Id,
+			})
+			if err == nil {
+				return nil
+			}
+
+			// Signing in with an account no viewer has while signed in links it to the signed in
+			// viewer, who can only have one account of each provider
+			_, err = app.Dao().FindExternalAuthByRecordAndProvider(e.Record, e.ProviderName)
+			if err == nil {
+				return fmt.Errorf("viewer already has a %s account", e.ProviderName)
+			}
+
+			return nil
+		}
+
+		if e.ProviderName != "twitch" {
+			return errors.New("viewers can only sign in with twitch")
+		}
+
+		displayName := e.OAuth2User.Name
+		if displayName == "" {
+			displayName = e.OAuth2User.Username
+		}
+
+		// The external auth is saved by pocketbase once the record is set
+		var record *models.Record
+		err := app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+			var err error
+			record, _, err = createViewerRecord(txDao, displayName)
+			return err
+		})
+		if err != nil {
+			app.Logger().Error(
+				"VIEWERS Failed to create viewer signing in",
+				"provider", e.ProviderName,
+				"providerId", e.OAuth2User.Id,
+				"error", err.Error(),
+			)
+			return err
+		}
+
+		e.Record = record
+		e.IsNewRecord = true
+
+		return nil
+	})
+
+	app.OnRecordAfterAuthWithOAuth2Request("viewers").Add(func(e *core.RecordAuthWithOAuth2Event) error {
+		if e.IsNewRecord {
+			giveDefaultProfileBase(e.Record.Id)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,197 @@
+package viewers
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+const EarnOnChatMessage = "chat-message"
+const EarnOnWatchMinute = "watch-minute"
+const EarnOnSubscription = "subscription"
+const EarnOnCheer = "cheer"
+const EarnOnRaid = "raid"
+
+var EarnOnAll = []string{
+	EarnOnChatMessage,
+	EarnOnWatchMinute,
+	EarnOnSubscription,
+	EarnOnCheer,
+	EarnOnRaid,
+}
+
+/*
+On - what earns the currency, one of chat-message, watch-minute, subscription, cheer or raid
+Amount - how much is earned each time
+Cooldown - seconds before a viewer can earn from the rule again, only used for chat messages
+PerBits - for cheers, earn the amount for every this many bits instead of once per cheer
+*/
+type EarningRule struct {
+	On       string `json:"on"`
+	Amount   int    `json:"amount"`
+	Cooldown int    `json:"cooldown"`
+	PerBits  int    `json:"perBits"`
+}
+
+/*
+Icon - the file name of the icon in the currency record
+StartingBalance - the balance new viewers start with
+CanGoNegative - whether debits can leave a viewer's balance below zero
+*/
+type Currency struct {
+	Id              string        `json:"id"`
+	Name            string        `json:"name"`
+	Icon            string        `json:"icon"`
+	StartingBalance int           `json:"startingBalance"`
+	CanGoNegative   bool          `json:"canGoNegative"`
+	Rules           []EarningRule `json:"rules"`
+}
+
+var currencies map[string]Currency = make(map[string]Currency)
+var currenciesLock sync.RWMutex
+
+func currencyFromRecord(record *models.Record) (Currency, error) {
+	rules := []EarningRule{}
+	if raw := record.GetString("rules"); raw != "" && raw != "null" {
+		err := record.UnmarshalJSONField("rules", &rules)
+		if err != nil {
+			return Currency{}, err
+		}
+	}
+
+	return Currency{
+		Id:              record.Id,
+		Name:            record.GetString("name"),
+		Icon:            record.GetString("icon"),
+		StartingBalance: record.GetInt("startingBalance"),
+		CanGoNegative:   record.GetBool("canGoNegative"),
+		Rules:           rules,
+	}, nil
+}
+
+func validateEarningRules(rules []EarningRule) error {
+	for _, rule := range rules {
+		if !slices.Contains(EarnOnAll, rule.On) {
+			return errors.New("unknown earning rule: " + rule.On)
+		}
+
+		if rule.Cooldown < 0 || rule.PerBits < 0 {
+			return errors.New("earning rule cooldown and bits can't be negative")
+		}
+	}
+
+	return nil
+}
+
+func GetCurrency(name string) (Currency, bool) {
+	currenciesLock.RLock()
+	defer currenciesLock.RUnlock()
+
+	currency, exists := currencies[name]
+	return currency, exists
+}
+
+// All defined currencies sorted by name
+func AllCurrencies() []Currency {
+	currenciesLock.RLock()
+	all := make([]Currency, 0, len(currencies))
+	for _, currency := range currencies {
+		all = append(all, currency)
+	}
+	currenciesLock.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name < all[j].Name
+	})
+
+	return all
+}
+
+// Currencies with a rule for something to earn on, paired with those rules
+func CurrenciesEarnedOn(on string) map[string][]EarningRule {
+	earned := map[string][]EarningRule{}
+	for _, currency := range AllCurrencies() {
+		for _, rule := range currency.Rules {
+			if rule.On == on {
+				earned[currency.Name] = append(earned[currency.Name], rule)
+			}
+		}
+	}
+
+	return earned
+}
+
+func LoadCurrencies() error {
+	records, err := pb.Dao().FindRecordsByExpr("currencies")
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[string]Currency, len(records))
+	for _, record := range records {
+		currency, err := currencyFromRecord(record)
+		if err != nil {
+			pb.Logger().Error(
+				"VIEWERS Failed to load currency",
+				"currency", record.GetString("name"),
+				"error", err.Error(),
+			)
+			continue
+		}
+
+		loaded[currency.Name] = currency
+	}
+
+	currenciesLock.Lock()
+	currencies = loaded
+	currenciesLock.Unlock()
+
+	return nil
+}
+
+func registerCurrencies(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		err := LoadCurrencies()
+		if err != nil {
+			app.Logger().Error("VIEWERS Failed to load currencies", "error", err.Error())
+		}
+
+		return nil
+	})
+
+	validate := func(record *models.Record) error {
+		currency, err := currencyFromRecord(record)
+		if err != nil {
+			return err
+		}
+
+		return validateEarningRules(currency.Rules)
+	}
+
+	app.OnRecordBeforeCreateRequest("currencies").Add(func(e *core.RecordCreateEvent) error {
+		return validate(e.Record)
+	})
+
+	app.OnRecordBeforeUpdateRequest("currencies").Add(func(e *core.RecordUpdateEvent) error {
+		return validate(e.Record)
+	})
+
+	// Reload on any change, renames need the old name dropped
+	reload := func(e *core.ModelEvent) error {
+		err := LoadCurrencies()
+		if err != nil {
+			app.Logger().Error("VIEWERS Failed to reload currencies", "error", err.Error())
+		}
+
+		return nil
+	}
+
+	app.OnModelAfterCreate("currencies").Add(reload)
+	app.OnModelAfterUpdate("currencies").Add(reload)
+	app.OnModelAfterDelete("currencies").Add(reload)
+}
@@ -0,0 +1,151 @@
+package viewers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+/*
+Id - the id of the viewer item, not the item
+Item - the id of the item
+Equipped - whether the item shows on the viewer's profile, always false for items that aren't profile items
+Slot - the layer an equipped accessory is drawn on
+Quantity - how many of the item are in the stack, always 1 for items that don't stack
+*/
+type ViewerItem struct {
+	Id          string `db:"id" json:"id"`
+	Item        string `db:"item" json:"item"`
+	Type        string `db:"type" json:"type"`
+	Label       string `db:"label" json:"label"`
+	Description string `db:"description" json:"description"`
+	Image       string `db:"image" json:"image"`
+	Equipped    bool   `db:"equipped" json:"equipped"`
+	Slot        int    `db:"slot" json:"slot"`
+	Stackable   bool   `db:"stackable" json:"stackable"`
+	Quantity    int    `db:"quantity" json:"quantity"`
+	Created     string `db:"created" json:"created"`
+}
+
+func isProfileItemType(itemType string) bool {
+	return itemType == "PROFILE_BASE" || itemType == "PROFILE_ACCESSORY"
+}
+
+// Gets every item a viewer owns, newest first
+func GetViewerItems(viewerId string) ([]ViewerItem, error) {
+	items := []ViewerItem{}
+
+	err := pb.Dao().DB().
+		Select(
+			"vi.id",
+			"i.id as item",
+			"i.type",
+			"i.label",
+			"i.description",
+			"i.image",
+			"(i.type IN ('PROFILE_BASE', 'PROFILE_ACCESSORY') AND "+equippedExpr+") as equipped",
+			"COALESCE(json_extract(vi.meta, '$.slot'), 0) as slot",
+			"i.stackable",
+			"vi.quantity",
+			"vi.created",
+		).
+		From("viewer_items as vi").
+		Join(
+			"INNER JOIN",
+			"items as i",
+			dbx.NewExp("vi.item = i.id"),
+		).
+		Where(dbx.NewExp("vi.owner = {:viewerId}", dbx.Params{"viewerId": viewerId})).
+		OrderBy("vi.created DESC", "vi.id DESC").
+		All(&items)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		if items[i].Image != "" {
+			items[i].Image = "/api/files/items/" + items[i].Item + "/" + items[i].Image
+		}
+	}
+
+	return items, nil
+}
+
+func registerInventoryAPIs(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.GET("/api/breakfast/viewers/:id/items", func(c echo.Context) error {
+			viewerId := c.PathParam("id")
+
+			// Validate user is authenticated, viewers can see their own items
+			if !HasPermission(c, viewerId, PermissionViewer) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			items, err := GetViewerItems(viewerId)
+			if err != nil {
+				return c.JSON(500, map[string]string{"message": "Failed to get viewer items", "error": err.Error()})
+			}
+
+			return c.JSON(200, map[string]any{
+				"items": items,
+			})
+		})
+
+		e.Router.POST("/api/breakfast/viewers/:id/items/:viewerItemId/equip", func(c echo.Context) error {
+			viewerId := c.PathParam("id")
+
+			// Validate user is authenticated, viewers can change their own profile
+			if !HasPermission(c, viewerId, PermissionViewer) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			// The slot is optional, accessories go in the first free slot without one
+			var body struct {
+				Slot *int `json:"slot"`
+			}
+
+			if c.Request().ContentLength > 0 {
+				err := c.Bind(&body)
+				if err != nil {
+					return c.JSON(400, map[string]string{"message": "Failed to parse slot"})
+				}
+			}
+
+			err := EquipItem(viewerId, c.PathParam("viewerItemId"), body.Slot)
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]string{"message": "Viewer doesn't own the item"})
+			}
+			if err != nil {
+				return c.JSON(400, map[string]string{"message": "Failed to equip the item", "error": err.Error()})
+			}
+
+			return respondWithLoadout(c, viewerId)
+		})
+
+		e.Router.POST("/api/breakfast/viewers/:id/items/:viewerItemId/unequip", func(c echo.Context) error {
+			viewerId := c.PathParam("id")
+
+			// Validate user is authenticated, viewers can change their own profile
+			if !HasPermission(c, viewerId, PermissionViewer) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			err := UnequipItem(viewerId, c.PathParam("viewerItemId"))
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]string{"message": "Viewer doesn't own the item"})
+			}
+			if err != nil {
+				return c.JSON(400, map[string]string{"message": "Failed to unequip the item", "error": err.Error()})
+			}
+
+			return respondWithLoadout(c, viewerId)
+		})
+
+		return nil
+	})
+}
@@ -0,0 +1,298 @@
+package viewers
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+)
+
+// How many accessories a viewer can wear at once, slots go from 0 to one less than this
+const MaxAccessorySlots = 8
+
+var ErrNotEquippable = errors.New("only profile items can be equipped")
+var ErrBaseRequired = errors.New("a base is always equipped, equip a different base instead")
+var ErrInvalidSlot = errors.New("slot doesn't exist")
+var ErrNoFreeSlot = errors.New("every accessory slot is taken")
+
+// The equipped state is kept in the meta of viewer items as {"equipped": true, "slot": 0}
+const equippedExpr = "COALESCE(json_extract(vi.meta, '$.equipped'), FALSE)"
+
+/*
+ViewerItem - the id of the viewer item
+Item - the id of the item
+Slot - the layer an accessory is drawn on, lower slots are drawn first. Always 0 for bases
+Image - the url of the item image
+*/
+type LoadoutItem struct {
+	ViewerItem string `json:"viewerItem"`
+	Item       string `json:"item"`
+	Slot       int    `json:"slot"`
+	Image      string `json:"image"`
+
+	file string
+}
+
+/*
+Base - the equipped base, nil when the viewer doesn't have one
+Accessories - the equipped accessories ordered by slot
+*/
+type Loadout struct {
+	Base        *LoadoutItem  `json:"base"`
+	Accessories []LoadoutItem `json:"accessories"`
+}
+
+type ownedProfileItem struct {
+	Id       string `db:"id"`
+	Item     string `db:"item"`
+	Type     string `db:"type"`
+	Image    string `db:"image"`
+	Equipped bool   `db:"equipped"`
+	Slot     int    `db:"slot"`
+}
+
+func getOwnedProfileItems(dao *daos.Dao, viewerId string) ([]ownedProfileItem, error) {
+	owned := []ownedProfileItem{}
+
+	err := dao.DB().
+		Select(
+			"vi.id",
+			"i.id as item",
+			"i.type",
+			"i.image",
+			equippedExpr+" as equipped",
+			"COALESCE(json_extract(vi.meta, '$.slot'), 0) as slot",
+		).
+		From("viewer_items as vi").
+		Join(
+			"INNER JOIN",
+			"items as i",
+			dbx.NewExp("vi.item = i.id"),
+		).
+		Where(dbx.NewExp(
+			"(i.type = 'PROFILE_BASE' OR i.type = 'PROFILE_ACCESSORY') AND vi.owner = {:viewerId}",
+			dbx.Params{"viewerId": viewerId},
+		)).
+		OrderBy("vi.created ASC", "vi.id ASC").
+		All(&owned)
+	if err != nil {
+		return nil, err
+	}
+
+	return owned, nil
+}
+
+// Merges a patch into the meta of a viewer item, nil values remove the key
+func patchItemMeta(txDao *daos.Dao, viewerItemId string, patch map[string]any) error {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	_, err = txDao.DB().
+		Update(
+			"viewer_items",
+			dbx.Params{
+				"meta": dbx.NewExp(
+					"json_patch(CASE WHEN json_type(meta) = 'object' THEN meta ELSE '{}' END, {:patch})",
+					dbx.Params{"patch": string(data)},
+				),
+				"updated": time.Now(),
+			},
+			dbx.HashExp{"id": viewerItemId},
+		).
+		Execute()
+
+	return err
+}
+
+// Gets the base and accessories a viewer has equipped
+func GetLoadout(viewerId string) (Loadout, error) {
+	loadout := Loadout{Accessories: []LoadoutItem{}}
+
+	owned, err := getOwnedProfileItems(pb.Dao(), viewerId)
+	if err != nil {
+		return loadout, err
+	}
+
+	for _, item := range owned {
+		if !item.Equipped {
+			continue
+		}
+
+		equipped := LoadoutItem{
+			ViewerItem: item.Id,
+			Item:       item.Item,
+			Image:      "/api/files/items/" + item.Item + "/" + item.Image,
+			file:       "items/" + item.Item + "/" + item.Image,
+		}
+
+		if item.Type == "PROFILE_BASE" {
+			loadout.Base = &equipped
+			continue
+		}
+
+		equipped.Slot = item.Slot
+		loadout.Accessories = append(loadout.Accessories, equipped)
+	}
+
+	sort.SliceStable(loadout.Accessories, func(i, j int) bool {
+		return loadout.Accessories[i].Slot < loadout.Accessories[j].Slot
+	})
+
+	return loadout, nil
+}
+
+// Equips one of a viewer's profile items. Equipping a base swaps out the current base. Accessories
+// go in the slot asked for, replacing what's there, or the first free slot when slot is nil.
+func EquipItem(viewerId string, viewerItemId string, slot *int) error {
+	if slot != nil && (*slot < 0 || *slot >= MaxAccessorySlots) {
+		return ErrInvalidSlot
+	}
+
+	defer InvalidateRenderedLoadout(viewerId)
+
+	return pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		owned, err := getOwnedProfileItems(txDao, viewerId)
+		if err != nil {
+			return err
+		}
+
+		var target *ownedProfileItem
+		for i := range owned {
+			if owned[i].Id == viewerItemId {
+				target = &owned[i]
+			}
+		}
+
+		if target == nil {
+			return checkOwnership(txDao, viewerId, viewerItemId)
+		}
+
+		if target.Type == "PROFILE_BASE" {
+			for _, item := range owned {
+				if item.Type != "PROFILE_BASE" || item.Id == target.Id || !item.Equipped {
+					continue
+				}
+
+				err := patchItemMeta(txDao, item.Id, map[string]any{"equipped": false})
+				if err != nil {
+					return err
+				}
+			}
+
+			return patchItemMeta(txDao, target.Id, map[string]any{"equipped": true})
+		}
+
+		taken := map[int]string{}
+		for _, item := range owned {
+			if item.Type == "PROFILE_ACCESSORY" && item.Equipped && item.Id != target.Id {
+				taken[item.Slot] = item.Id
+			}
+		}
+
+		chosen := -1
+		switch {
+		case slot != nil:
+			chosen = *slot
+		case target.Equipped:
+			// Already worn, leave it where it is
+			return nil
+		default:
+			for i := 0; i < MaxAccessorySlots; i++ {
+				if _, exists := taken[i]; !exists {
+					chosen = i
+					break
+				}
+			}
+
+			if chosen == -1 {
+				return ErrNoFreeSlot
+			}
+		}
+
+		if occupant, exists := taken[chosen]; exists {
+			err := patchItemMeta(txDao, occupant, map[string]any{"equipped": false, "slot": nil})
+			if err != nil {
+				return err
+			}
+		}
+
+		return patchItemMeta(txDao, target.Id, map[string]any{"equipped": true, "slot": chosen})
+	})
+}
+
+// Takes off one of a viewer's accessories. Bases can't be taken off, only swapped.
+func UnequipItem(viewerId string, viewerItemId string) error {
+	defer InvalidateRenderedLoadout(viewerId)
+
+	return pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		owned, err := getOwnedProfileItems(txDao, viewerId)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range owned {
+			if item.Id != viewerItemId {
+				continue
+			}
+
+			if item.Type == "PROFILE_BASE" {
+				return ErrBaseRequired
+			}
+
+			return patchItemMeta(txDao, item.Id, map[string]any{"equipped": false, "slot": nil})
+		}
+
+		return checkOwnership(txDao, viewerId, viewerItemId)
+	})
+}
+
+// Tells apart items the viewer doesn't own from owned items that can't be equipped
+func checkOwnership(txDao *daos.Dao, viewerId string, viewerItemId string) error {
+	var query struct {
+		Id string `db:"id"`
+	}
+
+	err := txDao.DB().
+		Select("id").
+		From("viewer_items").
+		Where(dbx.HashExp{"id": viewerItemId, "owner": viewerId}).
+		One(&query)
+	if err != nil {
+		return err
+	}
+
+	return ErrNotEquippable
+}
+
+// Equips the newest base a viewer owns when they don't have one equipped, so viewers always
+// have a base once they own one. Called after items are given to or taken from a viewer.
+func EnsureBaseEquipped(txDao *daos.Dao, viewerId string) error {
+	owned, err := getOwnedProfileItems(txDao, viewerId)
+	if err != nil {
+		return err
+	}
+
+	newest := ""
+	for _, item := range owned {
+		if item.Type != "PROFILE_BASE" {
+			continue
+		}
+
+		if item.Equipped {
+			return nil
+		}
+
+		newest = item.Id
+	}
+
+	if newest == "" {
+		return nil
+	}
+
+	return patchItemMeta(txDao, newest, map[string]any{"equipped": true})
+}
@@ -4,6 +4,7 @@ import (
 	bapis "breakfast/services/apis"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -12,6 +13,8 @@ import (
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	pbTypes "github.com/pocketbase/pocketbase/tools/types"
 )
 
 func registerManageAPIs(app *pocketbase.PocketBase) {
@@ -133,25 +136,19 @@ func registerManageAPIs(app *pocketbase.PocketBase) {
 		})
 
 		e.Router.GET("/api/breakfast/viewers/:id", func(c echo.Context) error {
-			// Validate user is authenticated
-			info := apis.RequestInfo(c)
-			user := info.AuthRecord
-
-			if user == nil {
-				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
-			}
+			viewerId := c.PathParam("id")
 
-			if user.Collection().Id != "users" {
+			// Validate user is authenticated, viewers can see themselves
+			if !HasPermission(c, viewerId, PermissionViewer) {
 				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
 			}
 
-			viewerId := c.PathParam("id")
-
 			var query struct {
-				Id          string `db:"id" json:"id"`
-				DisplayName string `db:"displayName" json:"displayName"`
-				Providers   string `db:"providers" json:"providers"`
-				ProviderIds string `db:"providerIds" json:"providerIds"`
+				Id          string          `db:"id" json:"id"`
+				DisplayName string          `db:"displayName" json:"displayName"`
+				Wallet      pbTypes.JsonMap `db:"wallet" json:"wallet"`
+				Providers   string          `db:"providers" json:"providers"`
+				ProviderIds string          `db:"providerIds" json:"providerIds"`
 			}
 
 			{
@@ -159,6 +156,7 @@ func registerManageAPIs(app *pocketbase.PocketBase) {
 					Select(
 						"v.id",
 						"v.displayName",
+						"v.wallet",
 						"group_concat(e.provider) as providers",
 						"group_concat(e.providerId) as providerIds",
 					).
@@ -198,21 +196,22 @@ func registerManageAPIs(app *pocketbase.PocketBase) {
 
 			viewerId := c.PathParam("id")
 
-			record, err := app.Dao().FindRecordById("viewers", viewerId)
-			if err != nil {
-				return c.JSON(400, map[string]string{"message": "Failed to get viewer"})
-			}
-
-			var wallet map[string]int
 			{
-				err := record.UnmarshalJSONField("wallet", &wallet)
+				_, err := app.Dao().FindRecordById("viewers", viewerId)
 				if err != nil {
-					return c.JSON(500, map[string]string{"message": "Viewer wallet is corrupt"})
+					return c.JSON(400, map[string]string{"message": "Failed to get viewer"})
 				}
 			}
 
 			data := apis.RequestInfo(c).Data
 
+			// An optional reason for the ledger, every number is a currency and the amount to add or remove
+			reason, _ := data["reason"].(string)
+			if reason == "" {
+				reason = "Changed by streamer"
+			}
+
+			changes := []WalletChange{}
 			for key, maybeAmount := range data {
 				amount := 0
 				switch maybeAmount.(type) {
@@ -224,28 +223,82 @@ func registerManageAPIs(app *pocketbase.PocketBase) {
 					continue
 				}
 
-				currentCount, exists := wallet[key]
-				if !exists {
-					currentCount = 0
+				if amount == 0 {
+					continue
 				}
 
-				_, err := app.Dao().DB().
-					NewQuery(
-						"UPDATE viewers SET wallet = json_patch(wallet, json_object({:currency}, {:amount})) WHERE id = {:id}",
-					).
-					Bind(dbx.Params{
-						"currency": key,
-						"amount":   currentCount + amount,
-						"id":       viewerId,
-					}).
-					Execute()
+				changes = append(changes, WalletChange{
+					Currency: key,
+					Amount:   amount,
+					Reason:   reason,
+					Actor:    "user:" + user.Id,
+				})
+			}
 
+			// All currencies change together or not at all
+			wallet := map[string]int{}
+			{
+				err := app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+					for _, change := range changes {
+						balance, err := ApplyWalletChange(txDao, viewerId, change, false)
+						if err != nil {
+							return fmt.Errorf("%s: %w", change.Currency, err)
+						}
+
+						wallet[change.Currency] = balance
+					}
+
+					return nil
+				})
+				if errors.Is(err, ErrInsufficientFunds) {
+					return c.JSON(400, map[string]string{"message": "Viewer doesn't have enough", "error": err.Error()})
+				}
 				if err != nil {
-					return c.JSON(400, map[string]string{"message": "Failed to update key: " + key})
+					return c.JSON(400, map[string]string{"message": "Failed to update wallet", "error": err.Error()})
 				}
 			}
 
-			return nil
+			return c.JSON(200, map[string]any{
+				"wallet": wallet,
+			})
+		})
+
+		e.Router.GET("/api/breakfast/viewers/:id/wallet/transactions", func(c echo.Context) error {
+			// Validate user is authenticated, viewers can see their own history
+			if !HasPermission(c, c.PathParam("id"), PermissionViewer) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			pageQuery := c.QueryParam("page")
+			perPageQuery := c.QueryParam("perPage")
+
+			if pageQuery == "" {
+				pageQuery = "1"
+			}
+
+			if perPageQuery == "" {
+				perPageQuery = "20"
+			}
+
+			page, err := strconv.Atoi(pageQuery)
+			if err != nil || page < 1 {
+				return c.JSON(400, map[string]string{"message": "Failed to parse page query"})
+			}
+			perPage, err := strconv.Atoi(perPageQuery)
+			if err != nil || perPage < 1 {
+				return c.JSON(400, map[string]string{"message": "Failed to parse per page query"})
+			}
+
+			transactions, err := GetWalletTransactions(c.PathParam("id"), c.QueryParam("currency"), page, perPage)
+			if err != nil {
+				return c.JSON(500, map[string]string{"message": "Failed to get wallet transactions", "error": err.Error()})
+			}
+
+			return c.JSON(200, map[string]any{
+				"page":         page,
+				"perPage":      perPage,
+				"transactions": transactions,
+			})
 		})
 
 		return nil
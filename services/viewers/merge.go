@@ -0,0 +1,367 @@
+package viewers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+var ErrMergeSelf = errors.New("a viewer can't be merged into itself")
+
+// Collections that point at a viewer, moved over to the viewer being merged into
+var mergedRelations = []struct {
+	Collection string
+	Field      string
+}{
+	{"wallet_transactions", "viewer"},
+	{"item_transactions", "viewer"},
+	{"shop_purchases", "viewer"},
+	{"loot_drops", "viewer"},
+	{"item_trades", "sender"},
+	{"item_trades", "recipient"},
+}
+
+// Counters kept outside of collections, added together when both viewers have a row
+var mergedCounters = []struct {
+	Table   string
+	Columns string
+	Key     string
+	Update  string
+}{
+	{
+		"viewer_watch_time",
+		"session, seconds, firstSeen, lastSeen",
+		"session, viewer",
+		"seconds = seconds + excluded.seconds, firstSeen = MIN(firstSeen, excluded.firstSeen), lastSeen = MAX(lastSeen, excluded.lastSeen)",
+	},
+	{
+		"emote_usage_viewers",
+		"channel, provider, emoteId, name, count",
+		"viewer, channel, provider, emoteId",
+		"count = count + excluded.count",
+	},
+	{
+		"loot_pity",
+		"lootBox, rarity, count",
+		"viewer, lootBox, rarity",
+		"count = count + excluded.count",
+	},
+}
+
+// Merges one viewer into another inside a transaction, then deletes the merged viewer. The
+// provider accounts, wallet, items, ledgers and stats of the merged viewer all move over.
+// Returns the provider accounts that moved so their cache can be invalidated once the
+// transaction is committed.
+func MergeViewersTx(txDao *daos.Dao, targetId string, sourceId string) ([]*models.ExternalAuth, error) {
+	if targetId == sourceId {
+		return nil, ErrMergeSelf
+	}
+
+	target, err := txDao.FindRecordById("viewers", targetId)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := txDao.FindRecordById("viewers", sourceId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Viewers can only have one account of each provider
+	externals, err := txDao.FindAllExternalAuthsByRecord(source)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, external := range externals {
+		_, err := txDao.FindExternalAuthByRecordAndProvider(target, external.Provider)
+		if err == nil {
+			return nil, fmt.Errorf("both viewers have a %s account", external.Provider)
+		}
+	}
+
+	{
+		_, err := txDao.DB().
+			Update(
+				"_externalAuths",
+				dbx.Params{"recordId": target.Id, "updated": time.Now()},
+				dbx.HashExp{"collectionId": "viewers", "recordId": source.Id},
+			).
+			Execute()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Trades between the two viewers would become trades with themselves
+	{
+		_, err := txDao.DB().
+			NewQuery(`UPDATE item_trades SET status = 'CANCELLED', updated = {:updated}
+				WHERE status = 'PENDING' AND ((sender = {:target} AND recipient = {:source}) OR (sender = {:source} AND recipient = {:target}))`).
+			Bind(dbx.Params{"target": target.Id, "source": source.Id, "updated": time.Now()}).
+			Execute()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, relation := range mergedRelations {
+		_, err := txDao.DB().
+			Update(
+				relation.Collection,
+				dbx.Params{relation.Field: target.Id},
+				dbx.HashExp{relation.Field: source.Id},
+			).
+			Execute()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	{
+		err := mergeViewerItems(txDao, target.Id, source.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The wallet ledger moved over with the rest, so the balances are added without a new entry
+	var wallet map[string]int
+	{
+		err := source.UnmarshalJSONField("wallet", &wallet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for currency, balance := range wallet {
+		if balance == 0 {
+			continue
+		}
+
+		err := incrementWallet(txDao, target.Id, currency, balance)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, counter := range mergedCounters {
+		_, err := txDao.DB().
+			NewQuery(fmt.Sprintf(
+				`INSERT INTO %s (viewer, %s) SELECT {:target}, %s FROM %s WHERE viewer = {:source}
+					ON CONFLICT (%s) DO UPDATE SET %s`,
+				counter.Table, counter.Columns, counter.Columns, counter.Table, counter.Key, counter.Update,
+			)).
+			Bind(dbx.Params{"target": target.Id, "source": source.Id}).
+			Execute()
+		if err != nil {
+			return nil, err
+		}
+
+		{
+			_, err := txDao.DB().
+				Delete(counter.Table, dbx.HashExp{"viewer": source.Id}).
+				Execute()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	{
+		err := txDao.DeleteRecord(source)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return externals, nil
+}
+
+// Moves a viewer's items to another viewer. Stacks are added to the other viewer's stack of the
+// same item, and the moved items are unequipped so the other viewer keeps their loadout.
+func mergeViewerItems(txDao *daos.Dao, targetId string, sourceId string) error {
+	var stacks []struct {
+		Id       string `db:"id"`
+		Quantity int    `db:"quantity"`
+		Stack    string `db:"stack"`
+	}
+
+	{
+		err := txDao.DB().
+			NewQuery(`SELECT vi.id, vi.quantity, COALESCE((
+					SELECT t.id FROM viewer_items as t WHERE t.owner = {:target} AND t.item = vi.item
+					ORDER BY t.created ASC, t.id ASC LIMIT 1
+				), '') as stack
+				FROM viewer_items as vi
+				INNER JOIN items as i ON vi.item = i.id
+				WHERE vi.owner = {:source} AND i.stackable = TRUE`).
+			Bind(dbx.Params{"target": targetId, "source": sourceId}).
+			All(&stacks)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, stack := range stacks {
+		if stack.Stack == "" {
+			continue
+		}
+
+		_, err := txDao.DB().
+			NewQuery("UPDATE viewer_items SET quantity = quantity + {:quantity}, updated = {:updated} WHERE id = {:id}").
+			Bind(dbx.Params{
+				"quantity": stack.Quantity,
+				"updated":  time.Now(),
+				"id":       stack.Stack,
+			}).
+			Execute()
+		if err != nil {
+			return err
+		}
+
+		{
+			_, err := txDao.DB().
+				Delete("viewer_items", dbx.HashExp{"id": stack.Id}).
+				Execute()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	{
+		_, err := txDao.DB().
+			NewQuery(`UPDATE viewer_items SET
+				owner = {:target},
+				meta = json_remove(json_set(COALESCE(meta, '{}'), '$.equipped', json('false')), '$.slot'),
+				updated = {:updated}
+				WHERE owner = {:source}`).
+			Bind(dbx.Params{"target": targetId, "source": sourceId, "updated": time.Now()}).
+			Execute()
+		if err != nil {
+			return err
+		}
+	}
+
+	// The other viewer may not have had a base of their own
+	return EnsureBaseEquipped(txDao, targetId)
+}
+
+// Merges one viewer into another and forgets which viewer the merged provider accounts belonged
+// to. Returns the viewer that was merged into.
+func MergeViewers(targetId string, sourceId string) (*Viewer, error) {
+	var externals []*models.ExternalAuth
+
+	err := pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		var err error
+		externals, err = MergeViewersTx(txDao, targetId, sourceId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, external := range externals {
+		providerToViewerIdCache.Delete(external.Provider + "-" + external.ProviderId)
+	}
+
+	InvalidateRenderedLoadout(targetId)
+	InvalidateRenderedLoadout(sourceId)
+
+	return GetViewerById(targetId)
+}
+
+func registerMergeAPIs(app *pocketbase.PocketBase) {
+	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
+		e.Router.POST("/api/breakfast/viewers/:id/merge", func(c echo.Context) error {
+			viewerId := c.PathParam("id")
+
+			// Validate user is authenticated
+			if !HasPermission(c, viewerId, PermissionUser) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			var body struct {
+				Viewer string `json:"viewer"`
+			}
+
+			{
+				err := c.Bind(&body)
+				if err != nil || body.Viewer == "" {
+					return c.JSON(400, map[string]string{"message": "A viewer to merge is needed"})
+				}
+			}
+
+			viewer, err := MergeViewers(viewerId, body.Viewer)
+			if errors.Is(err, sql.ErrNoRows) {
+				return c.JSON(404, map[string]string{"message": "Viewer not found"})
+			}
+			if err != nil {
+				return c.JSON(400, map[string]string{"message": "Failed to merge viewers", "error": err.Error()})
+			}
+
+			app.Logger().Info(
+				"VIEWERS Merged viewers",
+				"viewer", viewerId,
+				"merged", body.Viewer,
+				"user", apis.RequestInfo(c).AuthRecord.Id,
+			)
+
+			return c.JSON(200, viewer)
+		})
+
+		// Viewers link another account by signing in with it, then sending its token while signed
+		// in as themselves. Accounts no other viewer has are linked by pocketbase when signing in
+		// with them while already signed in, so this is for accounts that already have a viewer.
+		e.Router.POST("/api/breakfast/viewers/:id/link", func(c echo.Context) error {
+			viewerId := c.PathParam("id")
+
+			// Validate viewer is authenticated as themselves
+			if GetPermission(c, viewerId) != PermissionViewer {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Unauthorized"})
+			}
+
+			var body struct {
+				Token string `json:"token"`
+			}
+
+			{
+				err := c.Bind(&body)
+				if err != nil || body.Token == "" {
+					return c.JSON(400, map[string]string{"message": "The token of the account to link is needed"})
+				}
+			}
+
+			other, err := app.Dao().FindAuthRecordByToken(body.Token, app.Settings().RecordAuthToken.Secret)
+			if err != nil || other.Collection().Id != "viewers" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Token of the account to link is invalid"})
+			}
+
+			viewer, err := MergeViewers(viewerId, other.Id)
+			if err != nil {
+				return c.JSON(400, map[string]string{"message": "Failed to link accounts", "error": err.Error()})
+			}
+
+			app.Logger().Info(
+				"VIEWERS Viewer linked accounts",
+				"viewer", viewerId,
+				"merged", other.Id,
+			)
+
+			return c.JSON(200, viewer)
+		})
+
+		return nil
+	})
+}
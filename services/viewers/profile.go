@@ -1,8 +1,9 @@
 package viewers
 
 import (
+	"errors"
+
 	"github.com/labstack/echo/v5"
-	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/core"
 )
@@ -12,6 +13,15 @@ type ProfileItem struct {
 	Url  string `json:"url"`
 }
 
+func respondWithLoadout(c echo.Context, viewerId string) error {
+	loadout, err := GetLoadout(viewerId)
+	if err != nil {
+		return c.JSON(500, map[string]string{"message": "Failed to get viewer loadout", "error": err.Error()})
+	}
+
+	return c.JSON(200, loadout)
+}
+
 func registerProfileAPIs(app *pocketbase.PocketBase) {
 	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
 		e.Router.GET("/api/breakfast/viewers/:id/profile-items", func(c echo.Context) error {
@@ -20,54 +30,33 @@ func registerProfileAPIs(app *pocketbase.PocketBase) {
 				return c.JSON(400, map[string]string{"message": "Invalid viewer id"})
 			}
 
-			/*
-				SELECT i.type, i.image
-				FROM viewer_items as vi
-				INNER JOIN items as i ON vi.item = i.id
-				WHERE (i.type = 'PROFILE_BASE' OR i.type = 'PROFILE_ACCESSORY') AND vi.owner = {:viewerId};
-			*/
-
-			var query []struct {
-				Type   string `db:"type"`
-				ItemId string `db:"itemId"`
-				Image  string `db:"image"`
+			loadout, err := GetLoadout(viewerId)
+			if err != nil {
+				return c.JSON(500, map[string]string{"message": "Failed to get viewer profile items", "error": err.Error()})
 			}
-			{
-				err := app.Dao().DB().
-					Select(
-						"i.type",
-						"i.id as itemId",
-						"i.image",
-					).
-					From("viewer_items as vi").
-					Join(
-						"INNER JOIN",
-						"items as i",
-						dbx.NewExp("vi.item = i.id"),
-					).
-					Where(dbx.NewExp(
-						"(i.type = 'PROFILE_BASE' OR i.type = 'PROFILE_ACCESSORY') AND vi.owner = {:viewerId}",
-						dbx.Params{"viewerId": viewerId},
-					)).All(&query)
 
+			// Overlays that only want one image can have the loadout drawn for them
+			if c.QueryParam("format") == "png" {
+				image, err := RenderViewerLoadout(viewerId, loadout)
+				if errors.Is(err, ErrEmptyLoadout) {
+					return c.JSON(404, map[string]string{"message": "Viewer has nothing equipped"})
+				}
 				if err != nil {
-					return c.JSON(500, map[string]string{"message": "Failed to get viewer profile items", "error": err.Error()})
+					return c.JSON(500, map[string]string{"message": "Failed to render viewer profile", "error": err.Error()})
 				}
+
+				c.Response().Header().Set("Cache-Control", "no-cache")
+				return c.Blob(200, "image/png", image)
 			}
 
 			baseItem := ""
-			profileAccessoryItems := []string{}
-
-			for _, row := range query {
-				if row.Type == "PROFILE_BASE" {
-					baseItem = "/api/files/items/" + row.ItemId + "/" + row.Image
-					continue
-				}
+			if loadout.Base != nil {
+				baseItem = loadout.Base.Image
+			}
 
-				profileAccessoryItems = append(
-					profileAccessoryItems,
-					"/api/files/items/"+row.ItemId+"/"+row.Image,
-				)
+			profileAccessoryItems := []string{}
+			for _, accessory := range loadout.Accessories {
+				profileAccessoryItems = append(profileAccessoryItems, accessory.Image)
 			}
 
 			return c.JSON(200, map[string]any{
@@ -76,6 +65,15 @@ func registerProfileAPIs(app *pocketbase.PocketBase) {
 			})
 		})
 
+		e.Router.GET("/api/breakfast/viewers/:id/loadout", func(c echo.Context) error {
+			viewerId := c.PathParam("id")
+			if viewerId == "" {
+				return c.JSON(400, map[string]string{"message": "Invalid viewer id"})
+			}
+
+			return respondWithLoadout(c, viewerId)
+		})
+
 		return nil
 	})
 }
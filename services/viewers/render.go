@@ -0,0 +1,161 @@
+package viewers
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"time"
+
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/patrickmn/go-cache"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Largest profile item image file that's drawn
+const maxItemImageSize = 10 << 20
+
+// Widest or tallest profile item image that's drawn, checked before decoding
+const maxItemImageSide = 2048
+
+var ErrEmptyLoadout = errors.New("viewer has nothing equipped")
+var ErrItemImageTooLarge = errors.New("profile item image is too large")
+
+type renderedLoadout struct {
+	Signature string
+	Image     []byte
+}
+
+// Drawn loadouts keyed by viewer id, only used while the viewer's loadout still matches
+var renderedLoadouts *cache.Cache = cache.New(time.Hour, 10*time.Minute)
+
+// Identifies what a loadout draws, item files change name when their image is replaced
+func loadoutSignature(loadout Loadout) string {
+	files := []string{}
+	if loadout.Base != nil {
+		files = append(files, loadout.Base.file)
+	}
+	for _, accessory := range loadout.Accessories {
+		files = append(files, accessory.file)
+	}
+
+	return strings.Join(files, "|")
+}
+
+// Forgets a viewer's drawn loadout, called when what they have equipped may have changed
+func InvalidateRenderedLoadout(viewerId string) {
+	renderedLoadouts.Delete(viewerId)
+}
+
+func decodeItemImage(file string) (image.Image, error) {
+	fs, err := pb.NewFilesystem()
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Close()
+
+	reader, err := fs.GetFile(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxItemImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxItemImageSize {
+		return nil, ErrItemImageTooLarge
+	}
+
+	// Vector images like svgs aren't decoded and fail here
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Width > maxItemImageSide || config.Height > maxItemImageSide {
+		return nil, ErrItemImageTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+// Draws a loadout into one png. The canvas is the size of the base and accessories are stretched
+// over it in slot order, so layers are expected to be drawn at the same size as the base.
+func RenderLoadout(loadout Loadout) ([]byte, error) {
+	layers := []LoadoutItem{}
+	if loadout.Base != nil {
+		layers = append(layers, *loadout.Base)
+	}
+	layers = append(layers, loadout.Accessories...)
+
+	if len(layers) == 0 {
+		return nil, ErrEmptyLoadout
+	}
+
+	var canvas *image.RGBA
+	for _, layer := range layers {
+		decoded, err := decodeItemImage(layer.file)
+		if err != nil {
+			// A broken accessory shouldn't hide the rest of the profile
+			pb.Logger().Warn(
+				"ITEMS Failed to decode profile item image",
+				"item", layer.Item,
+				"error", err.Error(),
+			)
+			continue
+		}
+
+		if canvas == nil {
+			bounds := decoded.Bounds()
+			canvas = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		}
+
+		draw.CatmullRom.Scale(canvas, canvas.Bounds(), decoded, decoded.Bounds(), draw.Over, nil)
+	}
+
+	if canvas == nil {
+		return nil, ErrEmptyLoadout
+	}
+
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, canvas)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Draws a viewer's loadout, reusing the last drawing while the loadout hasn't changed
+func RenderViewerLoadout(viewerId string, loadout Loadout) ([]byte, error) {
+	signature := loadoutSignature(loadout)
+
+	if cached, exists := renderedLoadouts.Get(viewerId); exists {
+		rendered := cached.(renderedLoadout)
+		if rendered.Signature == signature {
+			return rendered.Image, nil
+		}
+	}
+
+	image, err := RenderLoadout(loadout)
+	if err != nil {
+		return nil, err
+	}
+
+	renderedLoadouts.SetDefault(viewerId, renderedLoadout{Signature: signature, Image: image})
+
+	return image, nil
+}
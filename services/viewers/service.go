@@ -9,9 +9,13 @@ var pb *pocketbase.PocketBase
 func RegisterService(app *pocketbase.PocketBase) {
 	pb = app
 
+	registerCurrencies(app)
+	registerViewerAuth(app)
 	registerManageAPIs(app)
 	registerStatAPIs(app)
 	registerItemsService(app)
+	registerInventoryAPIs(app)
 	registerProfileAPIs(app)
+	registerMergeAPIs(app)
 	registerCacheInvalidation(app)
 }
@@ -12,26 +12,9 @@ import (
 func registerStatAPIs(app *pocketbase.PocketBase) {
 	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
 		e.Router.GET("/api/breakfast/viewers/currencies", func(c echo.Context) error {
-			var query []struct {
-				Currency string
-			}
-
-			err := app.Dao().DB().
-				Select("json_each.key AS currency").
-				Distinct(true).
-				From("viewers, json_each(viewers.wallet, '$')").
-				All(&query)
-			if err != nil {
-				return c.JSON(500, map[string]string{"message": "Failed to query distinct currencies"})
-			}
-
 			currencies := []string{}
-			for _, row := range query {
-				if row.Currency == "" {
-					continue
-				}
-
-				currencies = append(currencies, row.Currency)
+			for _, currency := range AllCurrencies() {
+				currencies = append(currencies, currency.Name)
 			}
 
 			return c.JSON(200, map[string]any{"currencies": currencies})
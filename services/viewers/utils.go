@@ -42,53 +42,120 @@ func GetViewerById(id string) (*Viewer, error) {
 	}, nil
 }
 
-func CreateViewerByProviderId(provider string, id string) (*Viewer, error) {
-	viewer := Viewer{}
+// Saves a new viewer and gives them their starting balances. Linking the viewer to a provider
+// is up to the caller.
+func createViewerRecord(txDao *daos.Dao, displayName string) (*models.Record, *Viewer, error) {
+	collection, err := txDao.FindCollectionByNameOrId("viewers")
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// Create user and external auth records
-	err := pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
-		collection, err := txDao.FindCollectionByNameOrId("viewers")
+	viewerRecord := models.NewRecord(collection)
+	viewerRecord.MarkAsNew()
+	viewerRecord.RefreshId()
+	viewerRecord.RefreshTokenKey()
+	viewerRecord.SetUsername(security.RandomStringWithAlphabet(21, "abcdefghijklmnopqrstuvwxyz"))
+	viewerRecord.SetVerified(true)
+	viewerRecord.Set("displayName", displayName)
+	// Starting balances are given through the ledger once the viewer is saved
+	viewerRecord.Set("wallet", map[string]int{})
+
+	viewer := Viewer{
+		Id:          viewerRecord.Id,
+		DisplayName: displayName,
+		Wallet:      map[string]int{},
+	}
+
+	{
+		err := txDao.SaveRecord(viewerRecord)
 		if err != nil {
-			return err
+			return nil, nil, err
+		}
+	}
+
+	for _, currency := range AllCurrencies() {
+		if currency.StartingBalance == 0 {
+			continue
 		}
 
-		viewerRecord := models.NewRecord(collection)
-		viewerRecord.MarkAsNew()
-		viewerRecord.RefreshId()
-		viewerRecord.RefreshTokenKey()
-		viewerRecord.SetUsername(security.RandomStringWithAlphabet(21, "abcdefghijklmnopqrstuvwxyz"))
-		viewerRecord.SetVerified(true)
-		wallet := map[string]int{
-			"dots": 5,
+		balance, err := ApplyWalletChange(txDao, viewerRecord.Id, WalletChange{
+			Currency: currency.Name,
+			Amount:   currency.StartingBalance,
+			Reason:   "Starting balance",
+			Actor:    "system",
+		}, true)
+		if err != nil {
+			return nil, nil, err
 		}
-		viewerRecord.Set("wallet", wallet)
 
-		viewer.Id = viewerRecord.Id
-		viewer.Wallet = wallet
+		viewer.Wallet[currency.Name] = balance
+	}
 
-		switch provider {
-		case "twitch":
-			user, err := apis.GetTwitchUserById(id)
-			if err != nil {
-				pb.Logger().Error(
-					"VIEWERS Failed to create a twitch user",
-					"userId", id,
-					"error", err.Error(),
-				)
-				return err
-			}
-			viewerRecord.Set("displayName", user.DisplayName)
-			viewer.DisplayName = user.DisplayName
-		default:
-			pb.Logger().Warn("VIEWERS A viewer was created with an unknown provider")
+	// Keep the record in sync with the wallet the ledger wrote
+	viewerRecord.Set("wallet", viewer.Wallet)
+
+	return viewerRecord, &viewer, nil
+}
+
+// Gives a new viewer the default profile base item in the background, equipped since it's
+// their only base
+func giveDefaultProfileBase(viewerId string) {
+	if defaultProfileBaseItemId == "" {
+		return
+	}
+
+	go func() {
+		_, err := pb.Dao().DB().
+			Insert(
+				"viewer_items",
+				dbx.Params{
+					"id":       security.RandomString(15),
+					"owner":    viewerId,
+					"item":     defaultProfileBaseItemId,
+					"quantity": 1,
+					"meta":     `{"equipped":true}`,
+					"created":  time.Now(),
+					"updated":  time.Now(),
+				},
+			).
+			Execute()
+
+		if err != nil {
+			pb.Logger().Error(
+				"ITEMS Failed to give user default profile base item",
+				"error", err.Error(),
+			)
 		}
+	}()
+}
 
-		{
-			err := txDao.SaveRecord(viewerRecord)
-			if err != nil {
-				return err
-			}
+func CreateViewerByProviderId(provider string, id string) (*Viewer, error) {
+	displayName := ""
+	switch provider {
+	case "twitch":
+		user, err := apis.GetTwitchUserById(id)
+		if err != nil {
+			pb.Logger().Error(
+				"VIEWERS Failed to create a twitch user",
+				"userId", id,
+				"error", err.Error(),
+			)
+			return nil, err
+		}
+		displayName = user.DisplayName
+	default:
+		pb.Logger().Warn("VIEWERS A viewer was created with an unknown provider")
+	}
+
+	var viewer *Viewer
+
+	// Create user and external auth records
+	err := pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		viewerRecord, created, err := createViewerRecord(txDao, displayName)
+		if err != nil {
+			return err
 		}
+		viewer = created
 
 		external := models.ExternalAuth{
 			Provider:     provider,
@@ -116,32 +183,9 @@ func CreateViewerByProviderId(provider string, id string) (*Viewer, error) {
 	}
 
 	// Give user default profile base item
-	if defaultProfileBaseItemId != "" {
-		go func() {
-			_, err := pb.Dao().DB().
-				Insert(
-					"viewer_items",
-					dbx.Params{
-						"id":      security.RandomString(15),
-						"owner":   viewer.Id,
-						"item":    defaultProfileBaseItemId,
-						"meta":    nil,
-						"created": time.Now(),
-						"updated": time.Now(),
-					},
-				).
-				Execute()
+	giveDefaultProfileBase(viewer.Id)
 
-			if err != nil {
-				pb.Logger().Error(
-					"ITEMS Failed to give user default profile base item",
-					"error", err.Error(),
-				)
-			}
-		}()
-	}
-
-	return &viewer, nil
+	return viewer, nil
 }
 
 func GetViewerByProviderId(provider string, providerId string) (*Viewer, error) {
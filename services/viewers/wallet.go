@@ -0,0 +1,189 @@
+package viewers
+
+import (
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+var ErrInsufficientFunds = errors.New("viewer doesn't have enough to cover the debit")
+var ErrInvalidAmount = errors.New("amount must be more than zero")
+var ErrUnknownCurrency = errors.New("currency isn't defined")
+
+/*
+Reason - why the wallet changed, shown in the viewer's history
+Source - the id of the event that caused the change, e.g. a channel points redemption
+Actor - who made the change, e.g. "user:<id>" for a streamer or "twitch" for platform events
+*/
+type WalletChange struct {
+	Currency string
+	Amount   int
+	Reason   string
+	Source   string
+	Actor    string
+}
+
+type WalletTransaction struct {
+	Id       string `db:"id" json:"id"`
+	Viewer   string `db:"viewer" json:"viewer"`
+	Currency string `db:"currency" json:"currency"`
+	Delta    int    `db:"delta" json:"delta"`
+	Balance  int    `db:"balance" json:"balance"`
+	Reason   string `db:"reason" json:"reason"`
+	Source   string `db:"source" json:"source"`
+	Actor    string `db:"actor" json:"actor"`
+	Created  string `db:"created" json:"created"`
+}
+
+// Applies a change to a viewer's wallet and records it in the ledger. The balance is
+// incremented in place so concurrent changes can't overwrite each other. Overdrafts are
+// rejected unless allowed or the currency can go negative. Returns the new balance of
+// the currency.
+func ApplyWalletChange(txDao *daos.Dao, viewerId string, change WalletChange, allowOverdraft bool) (int, error) {
+	currency, exists := GetCurrency(change.Currency)
+	if !exists {
+		return 0, ErrUnknownCurrency
+	}
+
+	{
+		err := incrementWallet(txDao, viewerId, change.Currency, change.Amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var balance struct {
+		Value int `db:"value"`
+	}
+	{
+		err := txDao.DB().
+			NewQuery("SELECT value FROM viewers, json_each(viewers.wallet) WHERE viewers.id = {:id} AND key = {:currency}").
+			Bind(dbx.Params{
+				"currency": change.Currency,
+				"id":       viewerId,
+			}).
+			One(&balance)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if balance.Value < 0 && change.Amount < 0 && !allowOverdraft && !currency.CanGoNegative {
+		return 0, ErrInsufficientFunds
+	}
+
+	collection, err := txDao.FindCollectionByNameOrId("wallet_transactions")
+	if err != nil {
+		return 0, err
+	}
+
+	record := models.NewRecord(collection)
+	record.RefreshId()
+	record.Set("viewer", viewerId)
+	record.Set("currency", change.Currency)
+	record.Set("delta", change.Amount)
+	record.Set("balance", balance.Value)
+	record.Set("reason", change.Reason)
+	record.Set("source", change.Source)
+	record.Set("actor", change.Actor)
+
+	{
+		err := txDao.SaveRecord(record)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return balance.Value, nil
+}
+
+// Adds a positive or negative amount to a currency in a viewer's wallet, in place so concurrent
+// changes can't overwrite each other. Doesn't record anything in the ledger.
+func incrementWallet(txDao *daos.Dao, viewerId string, currency string, delta int) error {
+	result, err := txDao.DB().
+		NewQuery(`UPDATE viewers SET wallet = json_patch(
+			COALESCE(wallet, '{}'),
+			json_object(
+				{:currency},
+				COALESCE((SELECT value FROM json_each(COALESCE(wallet, '{}')) WHERE key = {:currency}), 0) + {:delta}
+			)
+		) WHERE id = {:id}`).
+		Bind(dbx.Params{
+			"currency": currency,
+			"delta":    delta,
+			"id":       viewerId,
+		}).
+		Execute()
+	if err != nil {
+		return err
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return errors.New("viewer not found")
+	}
+
+	return nil
+}
+
+func applyWalletChange(viewerId string, change WalletChange, allowOverdraft bool) (int, error) {
+	balance := 0
+	err := pb.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		var err error
+		balance, err = ApplyWalletChange(txDao, viewerId, change, allowOverdraft)
+		return err
+	})
+
+	return balance, err
+}
+
+// Adds an amount of a currency to a viewer's wallet
+func Credit(viewerId string, change WalletChange) (int, error) {
+	if change.Amount <= 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	return applyWalletChange(viewerId, change, false)
+}
+
+// Takes an amount of a currency from a viewer's wallet. Fails with ErrInsufficientFunds
+// instead of leaving the balance negative, unless the currency can go negative.
+func Debit(viewerId string, change WalletChange) (int, error) {
+	if change.Amount <= 0 {
+		return 0, ErrInvalidAmount
+	}
+
+	change.Amount = -change.Amount
+	return applyWalletChange(viewerId, change, false)
+}
+
+// Changes a viewer's balance by a positive or negative amount, even if it leaves the balance
+// negative. Meant for mirroring balances a platform keeps, like twitch channel points.
+func Adjust(viewerId string, change WalletChange) (int, error) {
+	return applyWalletChange(viewerId, change, true)
+}
+
+// Gets a page of a viewer's wallet transactions, newest first
+func GetWalletTransactions(viewerId string, currency string, page int, perPage int) ([]WalletTransaction, error) {
+	transactions := []WalletTransaction{}
+
+	query := pb.Dao().DB().
+		Select("id", "viewer", "currency", "delta", "balance", "reason", "source", "actor", "created").
+		From("wallet_transactions").
+		Where(dbx.HashExp{"viewer": viewerId}).
+		OrderBy("created DESC", "id DESC").
+		Limit(int64(perPage)).
+		Offset(int64(perPage) * int64(page-1))
+
+	if currency != "" {
+		query.AndWhere(dbx.HashExp{"currency": currency})
+	}
+
+	err := query.All(&transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
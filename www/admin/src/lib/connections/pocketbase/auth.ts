@@ -10,6 +10,7 @@ export const TWITCH_AUTH_SCOPES = [
   "channel:read:predictions",
   "channel:read:redemptions",
   "channel:read:subscriptions",
+  "moderator:read:chatters",
   "moderator:read:followers",
   "moderator:read:shoutouts",
   "user:read:chat",
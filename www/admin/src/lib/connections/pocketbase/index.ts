@@ -10,7 +10,14 @@ import { customAlphabet } from "nanoid";
 import { TWITCH_AUTH_SCOPES } from "./auth";
 import { PUBLIC_FEATURE_PROXY_AUTH_REDIRECT } from "$env/static/public";
 import type { Readable } from "svelte/store";
-import type { Action, Viewer } from "@brekkie/overlay";
+import type {
+  Action,
+  ChatMessageImage,
+  ItemGrantedEvent,
+  ItemTradedEvent,
+  LootBoxOpenedEvent,
+  Viewer,
+} from "@brekkie/overlay";
 
 export const streamKeyAlphabet = customAlphabet("abcdefghijklmnopqrstuvwxyz0123456789", 21);
 
@@ -60,6 +67,32 @@ type UserModel = {
   username: string;
 };
 
+export type ViewerItem = {
+  id: string;
+  item: string;
+  type: string;
+  label: string;
+  description: string;
+  image: string;
+  equipped: boolean;
+  slot: number;
+  stackable: boolean;
+  quantity: number;
+  created: string;
+};
+
+export type LoadoutItem = {
+  viewerItem: string;
+  item: string;
+  slot: number;
+  image: string;
+};
+
+export type Loadout = {
+  base: LoadoutItem | null;
+  accessories: LoadoutItem[];
+};
+
 /**
  * An auth store that has svelte stores instead of just plain js properties.
  */
@@ -237,13 +270,33 @@ export class BreakfastPocketBase extends PocketBase {
         },
         createSubscription: async (subscription: {
           type: string;
-          data: { broadcasterLogin: string };
+          condition?: Record<string, string>;
+          data?: { broadcasterLogin: string };
         }) => {
           await this.send("/api/breakfast/events/twitch/eventsub/subscribe", {
             method: "POST",
             body: JSON.stringify(subscription),
           });
         },
+        listSubscriptions: async (): Promise<{
+          broadcasters: {
+            broadcasterId: string;
+            subscriptions: {
+              id: string;
+              authorizer: string;
+              type: string;
+              version: string;
+              condition: Record<string, string>;
+              active: boolean;
+              status: string;
+              cost: number;
+            }[];
+          }[];
+          totalCost: number;
+          maxTotalCost: number;
+        }> => {
+          return await this.send("/api/breakfast/events/twitch/eventsub/subscriptions", {});
+        },
         deleteSubscription: async (id: string) => {
           await this.send(`/api/breakfast/events/twitch/eventsub/unsubscribe/${id}`, {
             method: "POST",
@@ -251,6 +304,145 @@ export class BreakfastPocketBase extends PocketBase {
         },
       },
     },
+    emotes: {
+      list: async (
+        filter: { channel?: string; provider?: string; search?: string } = {},
+      ): Promise<{
+        emotes: {
+          id: string;
+          name: string;
+          provider: string;
+          scope: "global" | "channel";
+          images: ChatMessageImage[];
+          zeroWidth: boolean;
+        }[];
+      }> => {
+        return await this.send("/api/breakfast/emotes", { query: filter });
+      },
+      usage: async (
+        filter: {
+          channel?: string;
+          range?: "today" | "stream" | "all";
+          viewer?: string;
+          limit?: number;
+        } = {},
+      ): Promise<{
+        emotes: {
+          provider: string;
+          emoteId: string;
+          name: string;
+          count: number;
+          images: ChatMessageImage[];
+        }[];
+      }> => {
+        return await this.send("/api/breakfast/emotes/usage", { query: filter });
+      },
+      refresh: async (channel?: string) => {
+        await this.send("/api/breakfast/emotes/refresh", {
+          method: "POST",
+          query: channel ? { channel } : {},
+        });
+      },
+      getProviderPrecedence: async (): Promise<{ precedence: string[]; available: string[] }> => {
+        return await this.send("/api/breakfast/emotes/settings/precedence", {});
+      },
+      setProviderPrecedence: async (precedence: string[]) => {
+        await this.send("/api/breakfast/emotes/settings/precedence", {
+          method: "POST",
+          body: JSON.stringify({ precedence }),
+        });
+      },
+      getProxyImages: async (): Promise<{ enabled: boolean }> => {
+        return await this.send("/api/breakfast/emotes/settings/proxy", {});
+      },
+      setProxyImages: async (enabled: boolean) => {
+        await this.send("/api/breakfast/emotes/settings/proxy", {
+          method: "POST",
+          body: JSON.stringify({ enabled }),
+        });
+      },
+    },
+    shop: {
+      list: async (
+        options?: SendOptions,
+      ): Promise<{
+        items: {
+          id: string;
+          type: string;
+          label: string;
+          description: string;
+          image: string;
+          prices: Record<string, number>;
+          stock: number | null;
+          perViewer: number;
+          availableFrom: string;
+          availableUntil: string;
+        }[];
+      }> => {
+        return await this.send("/api/breakfast/shop", options ?? {});
+      },
+      purchase: async (
+        purchase: { item: string; currency?: string; viewer?: string },
+        options?: SendOptions,
+      ) => {
+        return await this.send("/api/breakfast/shop/purchase", {
+          ...options,
+          method: "POST",
+          body: JSON.stringify(purchase),
+        });
+      },
+    },
+    trades: {
+      offer: async (
+        trade: {
+          recipient: string;
+          offered: { item: string; quantity: number }[];
+          requested?: { item: string; quantity: number }[];
+          sender?: string;
+        },
+        options?: SendOptions,
+      ) => {
+        return await this.send("/api/breakfast/trades", {
+          ...options,
+          method: "POST",
+          body: JSON.stringify(trade),
+        });
+      },
+      accept: async (tradeId: string, options?: SendOptions): Promise<ItemTradedEvent["data"]> => {
+        return await this.send(`/api/breakfast/trades/${tradeId}/accept`, {
+          ...options,
+          method: "POST",
+        });
+      },
+      decline: async (tradeId: string, options?: SendOptions) => {
+        return await this.send(`/api/breakfast/trades/${tradeId}/decline`, {
+          ...options,
+          method: "POST",
+        });
+      },
+      cancel: async (tradeId: string, options?: SendOptions) => {
+        return await this.send(`/api/breakfast/trades/${tradeId}/cancel`, {
+          ...options,
+          method: "POST",
+        });
+      },
+    },
+    channels: {
+      list: async (): Promise<{
+        channels: {
+          id: string;
+          provider: string;
+          providerId: string;
+          username: string;
+          displayName: string;
+          avatar: string;
+          label: string;
+          color: string;
+        }[];
+      }> => {
+        return await this.send("/api/breakfast/channels", {});
+      },
+    },
     viewers: {
       list: async (
         page: number = 1,
@@ -294,6 +486,75 @@ export class BreakfastPocketBase extends PocketBase {
       activeCurrencies: async (options?: SendOptions): Promise<{ currencies: string[] }> => {
         return await this.send("/api/breakfast/viewers/currencies", options ?? {});
       },
+      changeWallet: async (
+        viewerId: string,
+        amounts: Record<string, number>,
+        reason?: string,
+        options?: SendOptions,
+      ): Promise<{ wallet: Record<string, number> }> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/wallet/add`, {
+          ...options,
+          method: "POST",
+          body: JSON.stringify({ ...amounts, ...(reason ? { reason } : {}) }),
+        });
+      },
+      merge: async (
+        viewerId: string,
+        mergedViewerId: string,
+        options?: SendOptions,
+      ): Promise<Viewer> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/merge`, {
+          ...options,
+          method: "POST",
+          body: JSON.stringify({ viewer: mergedViewerId }),
+        });
+      },
+      walletTransactions: async (
+        viewerId: string,
+        page: number = 1,
+        perPage: number = 20,
+        currency: string = "",
+        options?: SendOptions,
+      ): Promise<{
+        page: number;
+        perPage: number;
+        transactions: {
+          id: string;
+          viewer: string;
+          currency: string;
+          delta: number;
+          balance: number;
+          reason: string;
+          source: string;
+          actor: string;
+          created: string;
+        }[];
+      }> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/wallet/transactions`, {
+          ...options,
+          query: {
+            ...options?.query,
+            page,
+            perPage,
+            ...(currency === "" ? {} : { currency }),
+          },
+        });
+      },
+      watchTime: async (
+        viewerId: string,
+        options?: SendOptions,
+      ): Promise<{
+        total: number;
+        sessions: {
+          session: string;
+          viewer: string;
+          seconds: number;
+          firstSeen: string;
+          lastSeen: string;
+        }[];
+      }> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/watch-time`, options ?? {});
+      },
       getDefaultProfileItem: async (options?: SendOptions): Promise<{ id: string }> => {
         return await this.send("/api/breakfast/viewers/default-profile-base", options ?? {});
       },
@@ -303,6 +564,72 @@ export class BreakfastPocketBase extends PocketBase {
       ): Promise<{ base: string; accessories: string[] }> => {
         return await this.send(`/api/breakfast/viewers/${viewerId}/profile-items`, options ?? {});
       },
+      items: async (
+        viewerId: string,
+        options?: SendOptions,
+      ): Promise<{ items: ViewerItem[] }> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/items`, options ?? {});
+      },
+      loadout: async (viewerId: string, options?: SendOptions): Promise<Loadout> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/loadout`, options ?? {});
+      },
+      profileImageUrl: (viewerId: string): string => {
+        return this.buildUrl(`/api/breakfast/viewers/${viewerId}/profile-items?format=png`);
+      },
+      equip: async (
+        viewerId: string,
+        viewerItemId: string,
+        slot?: number,
+        options?: SendOptions,
+      ): Promise<Loadout> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/items/${viewerItemId}/equip`, {
+          ...options,
+          method: "POST",
+          body: JSON.stringify(slot === undefined ? {} : { slot }),
+        });
+      },
+      grantItem: async (
+        viewerId: string,
+        item: { item: string; quantity?: number; reason?: string },
+        options?: SendOptions,
+      ): Promise<ItemGrantedEvent["data"]> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/items/grant`, {
+          ...options,
+          method: "POST",
+          body: JSON.stringify(item),
+        });
+      },
+      revokeItem: async (
+        viewerId: string,
+        item: { item: string; quantity?: number; reason?: string },
+        options?: SendOptions,
+      ): Promise<void> => {
+        await this.send(`/api/breakfast/viewers/${viewerId}/items/revoke`, {
+          ...options,
+          method: "POST",
+          body: JSON.stringify(item),
+        });
+      },
+      useItem: async (
+        viewerId: string,
+        viewerItemId: string,
+        options?: SendOptions,
+      ): Promise<Action | LootBoxOpenedEvent["data"]> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/items/${viewerItemId}/use`, {
+          ...options,
+          method: "POST",
+        });
+      },
+      unequip: async (
+        viewerId: string,
+        viewerItemId: string,
+        options?: SendOptions,
+      ): Promise<Loadout> => {
+        return await this.send(`/api/breakfast/viewers/${viewerId}/items/${viewerItemId}/unequip`, {
+          ...options,
+          method: "POST",
+        });
+      },
     },
   };
 
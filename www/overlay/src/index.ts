@@ -14,6 +14,11 @@ window.breakfast = {
     },
   },
   events: {
-    listen: (listener) => pb.realtime.subscribe("@breakfast/events", listener),
+    listen: (listener, options) =>
+      pb.realtime.subscribe(
+        "@breakfast/events",
+        listener,
+        options?.channel ? { query: { channel: [options.channel].flat().join(",") } } : undefined,
+      ),
   },
 };